@@ -0,0 +1,230 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// TaskArtifact is a flag value naming an artifact's contents: either a
+// directory or a tarball, optionally gzipped.
+type TaskArtifact string
+
+func (artifact *TaskArtifact) UnmarshalFlag(value string) error {
+	info, err := os.Stat(value)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() && !info.Mode().IsRegular() {
+		return fmt.Errorf("path '%s' is neither a directory nor a tarball", value)
+	}
+
+	abs, err := filepath.Abs(value)
+	if err != nil {
+		return err
+	}
+
+	*artifact = TaskArtifact(abs)
+
+	return nil
+}
+
+func (artifact TaskArtifact) Path() string {
+	return string(artifact)
+}
+
+// IsTarball reports whether the artifact is a file to extract rather than a
+// directory.
+func (artifact TaskArtifact) IsTarball() bool {
+	info, err := os.Stat(artifact.Path())
+	return err == nil && !info.IsDir()
+}
+
+// TaskArtifacts maps artifact names to their contents, given as 'name:path'
+// flags.
+type TaskArtifacts map[string]TaskArtifact
+
+// Complete completes the path of a 'name:path' value. The name is up to the
+// user.
+func (TaskArtifacts) Complete(match string) []flags.Completion {
+	colon := strings.Index(match, ":")
+	if colon == -1 {
+		return nil
+	}
+
+	name := match[:colon+1]
+
+	var completions []flags.Completion
+	for _, completion := range completePaths(match[colon+1:], false) {
+		completions = append(completions, flags.Completion{Item: name + completion.Item})
+	}
+
+	return completions
+}
+
+// extractTarball extracts the tarball into a new temporary directory, which
+// the caller is responsible for removing.
+func extractTarball(path string) (string, error) {
+	dir, err := ioutil.TempDir("", "pipe2proj-artifact")
+	if err != nil {
+		return "", err
+	}
+
+	err = readTarball(path, func(tarball *tar.Reader) error {
+		return untar(tarball, dir)
+	})
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return "", fmt.Errorf("extracting %s: %s", path, err)
+	}
+
+	return dir, nil
+}
+
+// loadTarball reads the tarball into memory, for when nothing should be
+// written to disk, even temporarily.
+func loadTarball(path string) (fs.FS, error) {
+	var files tarballFS
+	err := readTarball(path, func(tarball *tar.Reader) error {
+		var err error
+		files, err = untarInMemory(tarball)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %s", path, err)
+	}
+
+	return files, nil
+}
+
+// readTarball opens the tarball, which may be gzipped, and passes it to the
+// func.
+func readTarball(path string, fn func(*tar.Reader) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	var stream io.Reader = bufio.NewReader(file)
+
+	// detect gzip by its magic number rather than the file's extension
+	magic, err := stream.(*bufio.Reader).Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(stream)
+		if err != nil {
+			return err
+		}
+
+		defer gz.Close()
+
+		stream = gz
+	}
+
+	return fn(tar.NewReader(stream))
+}
+
+// untar writes the tarball's directories and regular files under the dir.
+// Other entries, e.g. symlinks, are skipped.
+func untar(tarball *tar.Reader, dir string) error {
+	for {
+		header, err := tarball.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		dest := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if dest != dir && !strings.HasPrefix(dest, dir+string(filepath.Separator)) {
+			return fmt.Errorf("entry '%s' is outside of the tarball", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err := os.MkdirAll(dest, 0755)
+			if err != nil {
+				return err
+			}
+
+		case tar.TypeReg:
+			err := os.MkdirAll(filepath.Dir(dest), 0755)
+			if err != nil {
+				return err
+			}
+
+			file, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&os.ModePerm)
+			if err != nil {
+				return err
+			}
+
+			_, err = io.Copy(file, tarball)
+			if err != nil {
+				file.Close()
+				return err
+			}
+
+			err = file.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// untarInMemory reads the tarball's directories and regular files into
+// memory. Other entries, e.g. symlinks, are skipped, as with untar.
+func untarInMemory(tarball *tar.Reader) (tarballFS, error) {
+	files := tarballFS{}
+	for {
+		header, err := tarball.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "/"))
+		if name == "." {
+			continue
+		}
+
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("entry '%s' is outside of the tarball", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			files.add(name, &tarballFile{
+				mode: fs.ModeDir | 0755,
+			})
+
+		case tar.TypeReg:
+			data, err := ioutil.ReadAll(tarball)
+			if err != nil {
+				return nil, err
+			}
+
+			files.add(name, &tarballFile{
+				data: data,
+				mode: fs.FileMode(header.Mode) & fs.ModePerm,
+			})
+		}
+	}
+}
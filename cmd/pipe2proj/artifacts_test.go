@@ -0,0 +1,132 @@
+package main
+
+import (
+	"archive/tar"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// artifactDir creates an artifact containing ci/unit.yml.
+func artifactDir(t testing.TB) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	err := os.MkdirAll(filepath.Join(dir, "ci"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "ci", "unit.yml"), []byte("platform: linux\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+// artifactTarball creates a tarball artifact containing ci/unit.yml.
+func artifactTarball(t testing.TB) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "repo.tar")
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	content := []byte("platform: linux\n")
+
+	tarball := tar.NewWriter(file)
+
+	err = tarball.WriteHeader(&tar.Header{
+		Name: "ci/unit.yml",
+		Mode: 0644,
+		Size: int64(len(content)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tarball.Write(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tarball.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestTaskArtifactsComplete(t *testing.T) {
+	dir := completionTree(t)
+	sep := string(filepath.Separator)
+
+	for _, test := range []struct {
+		title    string
+		match    string
+		expected []string
+	}{
+		{
+			title: "no name yet",
+			match: "ci",
+		},
+		{
+			title:    "path after the name",
+			match:    "ci:" + dir,
+			expected: []string{"ci:" + dir + "ci" + sep, "ci:" + dir + "ci.yml", "ci:" + dir + "pipeline.yml", "ci:" + dir + "tasks" + sep},
+		},
+		{
+			title:    "path with a prefix",
+			match:    "ci:" + dir + "ta",
+			expected: []string{"ci:" + dir + "tasks" + sep},
+		},
+		{
+			title: "only the first colon ends the name",
+			match: "ci:" + dir + "tasks:",
+		},
+		{
+			title: "no matching paths",
+			match: "ci:" + dir + "bogus",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			items := completionItems(TaskArtifacts{}.Complete(test.match))
+			if !reflect.DeepEqual(items, test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, items)
+			}
+		})
+	}
+}
+
+func TestLoadTarball(t *testing.T) {
+	files, err := loadTarball(artifactTarball(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ci has no entry of its own in the tarball
+	err = fstest.TestFS(files, "ci/unit.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := fs.ReadFile(files, "ci/unit.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != "platform: linux\n" {
+		t.Errorf("unexpected content: %q", content)
+	}
+}
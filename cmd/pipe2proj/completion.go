@@ -0,0 +1,41 @@
+package main
+
+// completionScripts hook each shell's completion up to go-flags, which
+// completes the command line given to it when $GO_FLAGS_COMPLETION is set.
+var completionScripts = map[string]string{
+	"bash": `_pipe2proj() {
+  local cur words cword
+  if declare -F _get_comp_words_by_ref >/dev/null; then
+    # keep 'name:path' artifacts together
+    _get_comp_words_by_ref -n : cur words cword
+  else
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    words=("${COMP_WORDS[@]}")
+    cword=$COMP_CWORD
+  fi
+
+  local IFS=$'\n'
+  COMPREPLY=($(GO_FLAGS_COMPLETION=1 "${words[0]}" "${words[@]:1:$cword}"))
+
+  if declare -F __ltrim_colon_completions >/dev/null; then
+    __ltrim_colon_completions "$cur"
+  fi
+}
+
+complete -o nospace -F _pipe2proj pipe2proj
+`,
+
+	"zsh": `#compdef pipe2proj
+
+_pipe2proj() {
+  local -a completions
+  completions=("${(@f)$(GO_FLAGS_COMPLETION=1 ${words[1]} "${(@)words[2,$CURRENT]}")}")
+  compadd -S '' -a completions
+}
+
+compdef _pipe2proj pipe2proj
+`,
+
+	"fish": `complete -c pipe2proj -f -a '(env GO_FLAGS_COMPLETION=1 pipe2proj (commandline -opc)[2..-1] (commandline -ct))'
+`,
+}
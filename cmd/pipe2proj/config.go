@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+
+	"github.com/jessevdk/go-flags"
+	"gopkg.in/yaml.v2"
+)
+
+func newParser(cmd *Command) *flags.Parser {
+	parser := flags.NewParser(cmd, flags.HelpFlag|flags.PassDoubleDash)
+	parser.NamespaceDelimiter = "-"
+	return parser
+}
+
+// configFilePath finds the --config flag (or $P2P_CONFIG) ahead of parsing
+// the rest of the flags, which the config file provides defaults for. Any
+// errors are left for the real parse to report.
+func configFilePath(args []string) string {
+	var cmd Command
+	parser := flags.NewParser(&cmd, flags.IgnoreUnknown|flags.PassDoubleDash)
+	parser.NamespaceDelimiter = "-"
+
+	// leave completion to the real parser
+	parser.CompletionHandler = func([]flags.Completion) {}
+
+	_, _ = parser.ParseArgs(args)
+
+	return string(cmd.ConfigFile)
+}
+
+// loadConfigFile reads options from a YAML file keyed by their long flag
+// names and makes them the defaults for the parser's options, so that
+// environment variables and flags take precedence over them. It returns the
+// options which were configured.
+func loadConfigFile(parser *flags.Parser, path string) (map[*flags.Option]bool, error) {
+	payload, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config yaml.MapSlice
+	err = yaml.Unmarshal(payload, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	defaults := map[*flags.Option][]string{}
+	for _, item := range config {
+		name, _ := item.Key.(string)
+
+		option := parser.FindOptionByLongName(name)
+		if option == nil || name == "config" {
+			return nil, fmt.Errorf("unknown option '%v'", item.Key)
+		}
+
+		values, err := configValues(option, item.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+
+		if option.Field().Type.Kind() == reflect.Bool {
+			if values[0] == "true" {
+				args = append(args, "--"+name)
+			} else if values[0] != "false" {
+				return nil, fmt.Errorf("%s: expected true or false, got '%s'", name, values[0])
+			}
+		} else {
+			for _, value := range values {
+				args = append(args, "--"+name+"="+value)
+			}
+		}
+
+		defaults[option] = values
+	}
+
+	// go-flags ignores invalid defaults, so check the values as flags first
+	var validate Command
+	_, err = newParser(&validate).ParseArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	configured := map[*flags.Option]bool{}
+	for option, values := range defaults {
+		option.Default = values
+		configured[option] = true
+	}
+
+	return configured, nil
+}
+
+// configValues converts a config file value into flag values: a mapping for
+// a map-valued flag, a list for one which can be given multiple times, or
+// a single value otherwise.
+func configValues(option *flags.Option, value interface{}) ([]string, error) {
+	var values []string
+
+	switch option.Field().Type.Kind() {
+	case reflect.Map:
+		mapping, ok := value.(yaml.MapSlice)
+		if !ok {
+			return nil, fmt.Errorf("expected a mapping")
+		}
+
+		for _, item := range mapping {
+			if !isScalar(item.Value) {
+				return nil, fmt.Errorf("expected a single value for '%v'", item.Key)
+			}
+
+			values = append(values, fmt.Sprintf("%v:%v", item.Key, item.Value))
+		}
+
+	case reflect.Slice:
+		list, ok := value.([]interface{})
+		if !ok {
+			list = []interface{}{value}
+		}
+
+		for _, val := range list {
+			if !isScalar(val) {
+				return nil, fmt.Errorf("expected a list of single values")
+			}
+
+			values = append(values, fmt.Sprint(val))
+		}
+
+	default:
+		if !isScalar(value) {
+			return nil, fmt.Errorf("expected a single value")
+		}
+
+		values = append(values, fmt.Sprint(value))
+	}
+
+	return values, nil
+}
+
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case yaml.MapSlice, []interface{}, nil:
+		return false
+	default:
+		return true
+	}
+}
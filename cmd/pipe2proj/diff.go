@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// limitDiffContext shortens the unchanged text between changes to the given
+// number of lines either side of each change, like 'diff -U', marking what
+// was left out with '...'.
+func limitDiffContext(diffs []diffmatchpatch.Diff, context int) []diffmatchpatch.Diff {
+	limited := make([]diffmatchpatch.Diff, len(diffs))
+	for i, diff := range diffs {
+		limited[i] = diff
+
+		if diff.Type != diffmatchpatch.DiffEqual {
+			continue
+		}
+
+		// the first and last lines are those of the changes either side,
+		// unless it's at the start or end
+		lines := strings.Split(diff.Text, "\n")
+
+		first := i == 0
+		last := i == len(diffs)-1
+
+		switch {
+		case first && last:
+			// nothing changed
+		case first:
+			if len(lines) > context+1 {
+				limited[i].Text = "...\n" + strings.Join(lines[len(lines)-context-1:], "\n")
+			}
+		case last:
+			if len(lines) > context+1 {
+				limited[i].Text = strings.Join(lines[:context+1], "\n") + "\n..."
+			}
+		default:
+			if len(lines) > 2*context+2 {
+				limited[i].Text = strings.Join(lines[:context+1], "\n") + "\n...\n" + strings.Join(lines[len(lines)-context-1:], "\n")
+			}
+		}
+	}
+
+	return limited
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/concourse/concourse/atc"
+	"github.com/vito/pipe2proj"
+	"gopkg.in/yaml.v2"
+)
+
+// doctorCheck is a preflight check, run by --doctor.
+type doctorCheck struct {
+	Name  string
+	Check func() error
+}
+
+// doctor runs the preflight checks for converting with the given flags,
+// without converting anything, and prints whether each passed.
+func (cmd *Command) doctor(w io.Writer) error {
+	checks := []doctorCheck{
+		{
+			Name:  "templates",
+			Check: cmd.checkTemplates,
+		},
+		{
+			Name: "project path",
+			Check: func() error {
+				return checkProjectPath(cmd.ProjectPath.Path())
+			},
+		},
+	}
+
+	opts := cmd.Options
+
+	var taskFiles []string
+	err := cmd.loadDoctorConfigs(&opts)
+	if err != nil {
+		checks = append(checks, doctorCheck{
+			Name:  "pipeline",
+			Check: func() error { return err },
+		})
+	}
+
+	pipelines := opts.Pipelines
+	if opts.Config != nil {
+		pipelines = append(pipelines, pipe2proj.Pipeline{
+			Config: opts.Config,
+			Source: opts.ConfigSource,
+		})
+	}
+
+	vars, varsErr := loadVars(cmd.VarsFiles)
+	checks = append(checks, doctorCheck{
+		Name:  "vars files",
+		Check: func() error { return varsErr },
+	})
+
+	for _, pipeline := range pipelines {
+		pipeline := pipeline
+		checks = append(checks, doctorCheck{
+			Name: fmt.Sprintf("pipeline %s", pipeline.Source),
+			Check: func() error {
+				files, err := checkPipeline(pipeline.Config, vars, cmd.MultiDoc)
+				taskFiles = append(taskFiles, files...)
+				return err
+			},
+		})
+	}
+
+	var artifactNames []string
+	for name := range cmd.TaskResources {
+		artifactNames = append(artifactNames, name)
+	}
+
+	sort.Strings(artifactNames)
+
+	// artifacts are checked last, against the task files of the pipelines
+	for _, name := range artifactNames {
+		name := name
+		checks = append(checks, doctorCheck{
+			Name: fmt.Sprintf("task artifact '%s'", name),
+			Check: func() error {
+				return checkArtifact(name, cmd.TaskResources[name], taskFiles)
+			},
+		})
+	}
+
+	failed := 0
+	for _, check := range checks {
+		err := check.Check()
+		if err != nil {
+			failed++
+			fmt.Fprintf(w, "FAIL  %s: %s\n", check.Name, err)
+		} else {
+			fmt.Fprintf(w, "ok    %s\n", check.Name)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+
+	return nil
+}
+
+// loadDoctorConfigs loads the pipeline configs as a conversion would, if any
+// were given.
+func (cmd *Command) loadDoctorConfigs(opts *pipe2proj.Options) error {
+	if len(cmd.PipelineConfigs) == 0 && cmd.FromPipeline == "" && cmd.PipelinesDir == "" && cmd.FetchTeam == "" {
+		return fmt.Errorf("no pipeline given")
+	}
+
+	return cmd.loadConfigs(opts)
+}
+
+// checkTemplates checks that each --config-templates dir exists and that the
+// templates in it, along with the builtin ones, parse.
+func (cmd *Command) checkTemplates() error {
+	for _, dir := range cmd.ConfigTemplates {
+		info, err := os.Stat(dir.Path())
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			return fmt.Errorf("'%s' is not a directory", dir.Path())
+		}
+	}
+
+	_, err := cmd.loadTemplates()
+	return err
+}
+
+// checkPipeline parses the pipeline config, returning the task files it
+// refers to. A pipeline with no jobs would convert to nothing, so it fails.
+func checkPipeline(config []byte, vars atc.Source, multiDoc string) ([]string, error) {
+	if len(bytes.TrimSpace(config)) == 0 {
+		return nil, fmt.Errorf("config is empty")
+	}
+
+	files, err := pipe2proj.TaskFiles(config, vars, multiDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	// each document is counted, as with --multi-doc=concat they all have
+	// jobs; documents of vars have none
+	jobs := 0
+	decoder := yaml.NewDecoder(bytes.NewReader(config))
+	for {
+		var doc struct {
+			Jobs []interface{} `yaml:"jobs"`
+		}
+
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		jobs += len(doc.Jobs)
+	}
+
+	if jobs == 0 {
+		return nil, fmt.Errorf("pipeline has no jobs")
+	}
+
+	return files, nil
+}
+
+// checkArtifact checks that the artifact can be read and that at least one of
+// the task files under its name is in it. An artifact which none of the task
+// files are under is probably misnamed.
+func checkArtifact(name string, artifact TaskArtifact, taskFiles []string) error {
+	info, err := os.Stat(artifact.Path())
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		dir, err := extractTarball(artifact.Path())
+		if err != nil {
+			return err
+		}
+
+		defer os.RemoveAll(dir)
+
+		artifact = TaskArtifact(dir)
+	}
+
+	prefix := name + "/"
+
+	var referenced []string
+	for _, file := range taskFiles {
+		if !strings.HasPrefix(file, prefix) {
+			continue
+		}
+
+		referenced = append(referenced, file)
+
+		_, err := os.Stat(filepath.Join(artifact.Path(), filepath.FromSlash(strings.TrimPrefix(file, prefix))))
+		if err == nil {
+			return nil
+		}
+	}
+
+	if len(referenced) == 0 {
+		return fmt.Errorf("no task file in the pipelines is under '%s'", prefix)
+	}
+
+	return fmt.Errorf("none of the %d task files under '%s' are in it, e.g. '%s'", len(referenced), prefix, referenced[0])
+}
+
+// checkProjectPath checks that files can be written into the project path,
+// or into the directory it would be created in if it doesn't exist yet.
+func checkProjectPath(path string) error {
+	if path == "" {
+		return fmt.Errorf("no --project-path given")
+	}
+
+	dir := path
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("'%s' is not a directory", dir)
+			}
+
+			break
+		}
+
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+
+		dir = parent
+	}
+
+	file, err := ioutil.TempFile(dir, ".pipe2proj-doctor-")
+	if err != nil {
+		return fmt.Errorf("cannot write to '%s': %s", dir, err)
+	}
+
+	file.Close()
+
+	return os.Remove(file.Name())
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const doctorPipeline = `---
+resources:
+- name: repo
+  type: git
+  source: {uri: https://example.com/repo.git}
+
+jobs:
+- name: unit
+  plan:
+  - get: repo
+  - task: unit
+    file: repo/ci/unit.yml
+`
+
+func TestCheckTemplates(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	notDir := filepath.Join(t.TempDir(), "git.tmpl")
+	err := os.WriteFile(notDir, []byte(gitTemplate), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	broken := t.TempDir()
+	err = os.WriteFile(filepath.Join(broken, "git.tmpl"), []byte("{{.Type"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		title string
+		dirs  []Dir
+		err   string
+	}{
+		{
+			title: "builtin templates",
+		},
+		{
+			title: "templates dir",
+			dirs:  []Dir{templatesDir(t)},
+		},
+		{
+			title: "missing templates dir",
+			dirs:  []Dir{Dir(missing)},
+			err:   "no such file or directory",
+		},
+		{
+			title: "templates dir is a file",
+			dirs:  []Dir{Dir(notDir)},
+			err:   "is not a directory",
+		},
+		{
+			title: "unparseable template",
+			dirs:  []Dir{Dir(broken)},
+			err:   "git.tmpl",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			cmd := &Command{ConfigTemplates: test.dirs}
+
+			err := cmd.checkTemplates()
+			checkDoctorError(t, err, test.err)
+		})
+	}
+}
+
+func TestCheckPipeline(t *testing.T) {
+	for _, test := range []struct {
+		title    string
+		config   string
+		multiDoc string
+		expected []string
+		err      string
+	}{
+		{
+			title:    "pipeline",
+			config:   doctorPipeline,
+			expected: []string{"repo/ci/unit.yml"},
+		},
+		{
+			title:    "jobs in a later document",
+			config:   "---\nresources: []\n---\n" + doctorPipeline,
+			multiDoc: "concat",
+			expected: []string{"repo/ci/unit.yml"},
+		},
+		{
+			title:  "empty config",
+			config: "\n",
+			err:    "config is empty",
+		},
+		{
+			title:  "pipeline with no jobs",
+			config: "resources:\n- name: repo\n  type: git\n",
+			err:    "pipeline has no jobs",
+		},
+		{
+			title:  "unparseable config",
+			config: "jobs: [",
+			err:    "yaml",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			files, err := checkPipeline([]byte(test.config), nil, test.multiDoc)
+			checkDoctorError(t, err, test.err)
+
+			if !reflect.DeepEqual(files, test.expected) {
+				t.Errorf("expected task files %q, got %q", test.expected, files)
+			}
+		})
+	}
+}
+
+func TestCheckArtifact(t *testing.T) {
+	corrupt := filepath.Join(t.TempDir(), "repo.tgz")
+	err := os.WriteFile(corrupt, []byte("\x1f\x8bnot really gzip"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		title     string
+		path      string
+		taskFiles []string
+		err       string
+	}{
+		{
+			title:     "dir",
+			path:      artifactDir(t),
+			taskFiles: []string{"repo/ci/unit.yml"},
+		},
+		{
+			title:     "tarball",
+			path:      artifactTarball(t),
+			taskFiles: []string{"repo/ci/unit.yml"},
+		},
+		{
+			title:     "one of the task files is in it",
+			path:      artifactDir(t),
+			taskFiles: []string{"repo/ci/missing.yml", "repo/ci/unit.yml"},
+		},
+		{
+			title:     "missing artifact",
+			path:      filepath.Join(t.TempDir(), "missing"),
+			taskFiles: []string{"repo/ci/unit.yml"},
+			err:       "no such file or directory",
+		},
+		{
+			title:     "unreadable tarball",
+			path:      corrupt,
+			taskFiles: []string{"repo/ci/unit.yml"},
+			err:       "extracting " + corrupt,
+		},
+		{
+			title:     "unresolvable task file path",
+			path:      artifactDir(t),
+			taskFiles: []string{"repo/ci/missing.yml", "other/ci/unit.yml"},
+			err:       "none of the 1 task files under 'repo/' are in it, e.g. 'repo/ci/missing.yml'",
+		},
+		{
+			title:     "no task files under the name",
+			path:      artifactDir(t),
+			taskFiles: []string{"other/ci/unit.yml"},
+			err:       "no task file in the pipelines is under 'repo/'",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			err := checkArtifact("repo", TaskArtifact(test.path), test.taskFiles)
+			checkDoctorError(t, err, test.err)
+		})
+	}
+}
+
+func TestCheckProjectPath(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	err := os.WriteFile(file, nil, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readOnly := filepath.Join(t.TempDir(), "read-only")
+	err = os.Mkdir(readOnly, 0555)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		title      string
+		path       string
+		err        string
+		skipAsRoot bool
+	}{
+		{
+			title: "existing dir",
+			path:  t.TempDir(),
+		},
+		{
+			title: "dir to create",
+			path:  filepath.Join(t.TempDir(), "project", "nested"),
+		},
+		{
+			title: "no project path",
+			err:   "no --project-path given",
+		},
+		{
+			title: "project path is a file",
+			path:  file,
+			err:   "'" + file + "' is not a directory",
+		},
+		{
+			title: "under a file",
+			path:  filepath.Join(file, "project"),
+			err:   "not a directory",
+		},
+		{
+			// root can write anywhere, so this only fails for other users
+			title:      "unwritable project path",
+			path:       filepath.Join(readOnly, "project"),
+			err:        "cannot write to '" + readOnly + "'",
+			skipAsRoot: true,
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			if test.skipAsRoot && os.Geteuid() == 0 {
+				t.Skip("running as root")
+			}
+
+			err := checkProjectPath(test.path)
+			checkDoctorError(t, err, test.err)
+		})
+	}
+}
+
+// checkDoctorError checks that the check passed, or failed with an error
+// containing the given message.
+func checkDoctorError(t *testing.T, err error, expected string) {
+	t.Helper()
+
+	if expected == "" {
+		if err != nil {
+			t.Fatalf("expected the check to pass, got: %s", err)
+		}
+
+		return
+	}
+
+	if err == nil {
+		t.Fatalf("expected the check to fail with '%s'", expected)
+	}
+
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected an error containing '%s', got: %s", expected, err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vito/pipe2proj"
+)
+
+// exit codes, so that scripts can tell a hand-edited file from a broken
+// pipeline, or from one which Concourse would reject
+const (
+	exitFailed     = 1
+	exitConflict   = 2
+	exitValidation = 3
+	exitTarget     = 4
+
+	// as if killed by SIGINT, as shells report it
+	exitInterrupted = 130
+)
+
+// ConflictError is returned when a file in the project has content other
+// than what would be generated.
+type ConflictError struct {
+	Path string
+	Diff string
+}
+
+func (err ConflictError) Error() string {
+	return fmt.Sprintf("path %s already has different content:\n\n%s", err.Path, err.Diff)
+}
+
+// InterruptedError is returned when the conversion is interrupted, e.g. by
+// Ctrl-C, listing the files which were written before it stopped.
+type InterruptedError struct {
+	Written []string
+}
+
+func (err InterruptedError) Error() string {
+	if len(err.Written) == 0 {
+		return "interrupted before writing any files"
+	}
+
+	return fmt.Sprintf("interrupted after writing %d files:\n\n  %s", len(err.Written), strings.Join(err.Written, "\n  "))
+}
+
+// exitCode determines the exit code for the error.
+func exitCode(err error) int {
+	var interrupted InterruptedError
+	if errors.As(err, &interrupted) {
+		return exitInterrupted
+	}
+
+	var conflict ConflictError
+	if errors.As(err, &conflict) {
+		return exitConflict
+	}
+
+	var target TargetValidationError
+	if errors.As(err, &target) {
+		return exitTarget
+	}
+
+	var invalid pipe2proj.ValidationError
+	if errors.As(err, &invalid) {
+		return exitValidation
+	}
+
+	return exitFailed
+}
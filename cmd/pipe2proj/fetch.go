@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// flyrc is the part of fly's ~/.flyrc needed to talk to its targets.
+type flyrc struct {
+	Targets map[string]flyTarget `yaml:"targets"`
+}
+
+type flyTarget struct {
+	API      string    `yaml:"api"`
+	Team     string    `yaml:"team"`
+	Insecure bool      `yaml:"insecure"`
+	CACert   string    `yaml:"ca_cert"`
+	Token    *flyToken `yaml:"token"`
+}
+
+type flyToken struct {
+	Type  string `yaml:"type"`
+	Value string `yaml:"value"`
+}
+
+// the most times a request is retried when rate limited, and the longest to
+// wait between attempts
+const (
+	maxRateLimitRetries = 5
+	maxRateLimitWait    = 30 * time.Second
+)
+
+// concourse talks to the API of a fly target's Concourse, authenticating as
+// fly would. Requests are abandoned once the context is cancelled.
+type concourse struct {
+	ctx        context.Context
+	targetName string
+	target     flyTarget
+	client     *http.Client
+}
+
+// statusError is returned when the API responds with an unexpected status.
+type statusError struct {
+	URL    string
+	Status string
+	Code   int
+}
+
+func (err statusError) Error() string {
+	return fmt.Sprintf("unexpected response from %s: %s", err.URL, err.Status)
+}
+
+// pipelineNotFoundError is returned when the pipeline isn't in the team.
+type pipelineNotFoundError struct {
+	Pipeline string
+	Team     string
+}
+
+func (err pipelineNotFoundError) Error() string {
+	return fmt.Sprintf("pipeline '%s' not found in team '%s'", err.Pipeline, err.Team)
+}
+
+// atcPipeline is the part of a pipeline listed by the API needed to fetch and
+// name it. Archiving and instance vars are only known to newer versions of
+// Concourse.
+type atcPipeline struct {
+	Name         string                 `json:"name"`
+	Paused       bool                   `json:"paused"`
+	Archived     bool                   `json:"archived"`
+	InstanceVars map[string]interface{} `json:"instance_vars"`
+}
+
+func newConcourse(ctx context.Context, targetName string) (*concourse, error) {
+	target, err := loadFlyTarget(targetName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := target.client()
+	if err != nil {
+		return nil, err
+	}
+
+	return &concourse{
+		ctx:        ctx,
+		targetName: targetName,
+		target:     target,
+		client:     client,
+	}, nil
+}
+
+// fetchPipelineConfig fetches the pipeline's config from the Concourse of
+// the given fly target. The team defaults to the target's. It returns the
+// config as YAML along with the URL it came from.
+func fetchPipelineConfig(ctx context.Context, targetName string, team string, pipeline string) ([]byte, string, error) {
+	api, err := newConcourse(ctx, targetName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return api.pipelineConfig(api.team(team), atcPipeline{Name: pipeline})
+}
+
+// team defaults the team to the target's.
+func (api *concourse) team(team string) string {
+	if team == "" {
+		return api.target.Team
+	}
+
+	return team
+}
+
+// pipelineConfig fetches the pipeline's config, returning it as YAML along
+// with the URL it came from.
+func (api *concourse) pipelineConfig(team string, pipeline atcPipeline) ([]byte, string, error) {
+	query := url.Values{}
+	for key, val := range pipeline.InstanceVars {
+		payload, err := json.Marshal(val)
+		if err != nil {
+			return nil, "", err
+		}
+
+		query.Set("vars."+key, string(payload))
+	}
+
+	var response struct {
+		Config json.RawMessage `json:"config"`
+	}
+
+	configURL, err := api.get(fmt.Sprintf("/api/v1/teams/%s/pipelines/%s/config", url.PathEscape(team), url.PathEscape(pipeline.Name)), query, &response)
+	if err != nil {
+		var status statusError
+		if errors.As(err, &status) {
+			switch status.Code {
+			case http.StatusUnauthorized, http.StatusForbidden:
+				return nil, "", fmt.Errorf("not authorized to get pipeline '%s' in team '%s'; try 'fly -t %s login'", pipeline.Name, team, api.targetName)
+			case http.StatusNotFound:
+				return nil, "", pipelineNotFoundError{
+					Pipeline: pipeline.Name,
+					Team:     team,
+				}
+			}
+		}
+
+		return nil, "", err
+	}
+
+	// convert to YAML, keeping the key order, so that errors refer to
+	// meaningful lines
+	var config yaml.MapSlice
+	err = yaml.Unmarshal(response.Config, &config)
+	if err != nil {
+		return nil, "", fmt.Errorf("decoding config: %s", err)
+	}
+
+	payload, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return payload, configURL, nil
+}
+
+// pipelines lists the team's pipelines.
+func (api *concourse) pipelines(team string) ([]atcPipeline, error) {
+	var pipelines []atcPipeline
+	_, err := api.get(fmt.Sprintf("/api/v1/teams/%s/pipelines", url.PathEscape(team)), nil, &pipelines)
+	if err != nil {
+		var status statusError
+		if errors.As(err, &status) {
+			switch status.Code {
+			case http.StatusUnauthorized, http.StatusForbidden:
+				return nil, fmt.Errorf("not authorized to list pipelines in team '%s'; try 'fly -t %s login -n %s'", team, api.targetName, team)
+			case http.StatusNotFound:
+				return nil, fmt.Errorf("team '%s' not found", team)
+			}
+		}
+
+		return nil, err
+	}
+
+	return pipelines, nil
+}
+
+// get decodes the JSON response to a GET of the API path, returning the URL
+// it came from. Requests which are rate limited are retried after the
+// Retry-After given, or a second if there isn't one.
+func (api *concourse) get(path string, query url.Values, response interface{}) (string, error) {
+	reqURL := strings.TrimSuffix(api.target.API, "/") + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(api.ctx, "GET", reqURL, nil)
+		if err != nil {
+			return "", err
+		}
+
+		if api.target.Token != nil {
+			req.Header.Set("Authorization", api.target.Token.Type+" "+api.target.Token.Value)
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"url": reqURL,
+		}).Info("fetching")
+
+		res, err := api.client.Do(req)
+		if err != nil {
+			return "", err
+		}
+
+		if res.StatusCode == http.StatusTooManyRequests && attempt < maxRateLimitRetries {
+			res.Body.Close()
+
+			wait := retryAfter(res.Header.Get("Retry-After"))
+
+			logrus.WithFields(logrus.Fields{
+				"url":  reqURL,
+				"wait": wait,
+			}).Warn("rate limited; retrying")
+
+			select {
+			case <-time.After(wait):
+			case <-api.ctx.Done():
+				return "", api.ctx.Err()
+			}
+
+			continue
+		}
+
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return "", statusError{
+				URL:    reqURL,
+				Status: res.Status,
+				Code:   res.StatusCode,
+			}
+		}
+
+		err = json.NewDecoder(res.Body).Decode(response)
+		if err != nil {
+			return "", fmt.Errorf("decoding response: %s", err)
+		}
+
+		return reqURL, nil
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds, capping the wait.
+func retryAfter(header string) time.Duration {
+	wait := time.Second
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		wait = time.Duration(seconds) * time.Second
+	}
+
+	if wait > maxRateLimitWait {
+		wait = maxRateLimitWait
+	}
+
+	return wait
+}
+
+func loadFlyTarget(name string) (flyTarget, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return flyTarget{}, err
+	}
+
+	payload, err := ioutil.ReadFile(filepath.Join(home, ".flyrc"))
+	if err != nil {
+		return flyTarget{}, err
+	}
+
+	var rc flyrc
+	err = yaml.Unmarshal(payload, &rc)
+	if err != nil {
+		return flyTarget{}, fmt.Errorf("parsing .flyrc: %s", err)
+	}
+
+	target, found := rc.Targets[name]
+	if !found {
+		return flyTarget{}, fmt.Errorf("unknown target '%s'; add it with 'fly -t %s login'", name, name)
+	}
+
+	return target, nil
+}
+
+func (target flyTarget) client() (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: target.Insecure,
+	}
+
+	if target.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM([]byte(target.CACert)) {
+			return nil, fmt.Errorf("target has an invalid CA certificate")
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/vito/pipe2proj"
+)
+
+// writeGraph writes the graph to the path, choosing the format by its
+// extension.
+func writeGraph(path string, graph pipe2proj.Graph) error {
+	buf := new(bytes.Buffer)
+
+	var err error
+	switch filepath.Ext(path) {
+	case ".mmd", ".mermaid":
+		err = graph.WriteMermaid(buf)
+	default:
+		err = graph.WriteDOT(buf)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
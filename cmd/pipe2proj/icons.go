@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// loadIcons reads a mapping of resource types to icons.
+func loadIcons(file File) (map[string]string, error) {
+	payload, err := ioutil.ReadFile(file.Path())
+	if err != nil {
+		return nil, err
+	}
+
+	var icons map[string]string
+	err = yaml.UnmarshalStrict(payload, &icons)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", file.Path(), err)
+	}
+
+	return icons, nil
+}
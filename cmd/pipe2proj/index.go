@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"text/template"
+
+	"github.com/vito/pipe2proj"
+)
+
+// renderIndex renders the index template with the manifest of the generated
+// files, e.g. to list them in a kustomization.yaml.
+func renderIndex(templatePath string, files []pipe2proj.GeneratedFile) ([]byte, error) {
+	content, err := ioutil.ReadFile(templatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(filepath.Base(templatePath)).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	payload := new(bytes.Buffer)
+	err = tmpl.Execute(payload, newManifest(files))
+	if err != nil {
+		return nil, err
+	}
+
+	return payload.Bytes(), nil
+}
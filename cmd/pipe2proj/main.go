@@ -0,0 +1,963 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/sirupsen/logrus"
+	"github.com/vito/pipe2proj"
+)
+
+// matches valid Concourse identifiers, e.g. pipeline names
+var identifierRegexp = regexp.MustCompile(`^[\p{Ll}\p{Lt}\p{Lm}\p{Lo}\d][\p{Ll}\p{Lt}\p{Lm}\p{Lo}\d\-_.]*$`)
+
+type Command struct {
+	pipe2proj.Options
+
+	ConfigFile File `long:"config" value-name:"PATH" env:"P2P_CONFIG" description:"YAML file of options keyed by their long flag names, e.g. 'project-name: ci'. Environment variables and flags take precedence."`
+
+	ProjectPath Dir `long:"project-path" short:"j" env:"P2P_PROJECT_PATH" description:"Project path to convert into."`
+
+	PipelineConfigs []File `long:"pipeline-config" short:"c" env:"P2P_PIPELINE_CONFIG" env-delim:"," description:"Path to pipeline config. Can be given multiple times to merge several pipelines into the one named by --pipeline-name."`
+
+	PipelinesDir Dir `long:"pipelines-dir" value-name:"DIR" env:"P2P_PIPELINES_DIR" description:"Directory of pipeline configs to convert in place of --pipeline-config, naming each pipeline after its file, e.g. 'main' for 'main.yml'."`
+
+	Target       string `long:"target" value-name:"TARGET" env:"P2P_TARGET" description:"fly target to fetch --from-pipeline from, authenticating with its token from ~/.flyrc."`
+	Team         string `long:"team" value-name:"TEAM" env:"P2P_TEAM" description:"Team to fetch --from-pipeline from, or to --validate-against. Defaults to the target's team."`
+	FromPipeline string `long:"from-pipeline" value-name:"NAME" env:"P2P_FROM_PIPELINE" description:"Fetch the config of the named pipeline from --target's Concourse rather than reading --pipeline-config."`
+
+	FetchTeam    string `long:"fetch-team" value-name:"TEAM" env:"P2P_FETCH_TEAM" description:"Fetch and convert every pipeline in the team from --target's Concourse. Implies --keep-going."`
+	SkipPaused   bool   `long:"skip-paused" env:"P2P_SKIP_PAUSED" description:"Leave paused pipelines out of --fetch-team."`
+	SkipArchived bool   `long:"skip-archived" env:"P2P_SKIP_ARCHIVED" description:"Leave archived pipelines out of --fetch-team."`
+
+	ValidateAgainst string `long:"validate-against" value-name:"TARGET" env:"P2P_VALIDATE_AGAINST" description:"Once converted, validate each pipeline with the atc validation bundled with pipe2proj, then show how it differs from the pipeline already set on the fly target. Nothing is set."`
+
+	TaskResources TaskArtifacts `long:"task-artifact" short:"t" env:"P2P_TASK_ARTIFACT" env-delim:"," description:"Mapping from artifact name to local directory or tarball, used for converting tasks."`
+
+	IconsFile File `long:"icons" value-name:"PATH" env:"P2P_ICONS" description:"YAML file mapping resource types to icons for --auto-icons, e.g. 'my-type: rocket', in addition to or in place of the defaults."`
+
+	VarsFiles []File `long:"vars-file" short:"l" value-name:"PATH" env:"P2P_VARS_FILE" env-delim:"," description:"File of vars to interpolate into task file paths, e.g. 'file: ((tasks))/build.yml'. Can be given multiple times; later files take precedence."`
+
+	ConfigTemplates []Dir `long:"config-templates" env:"P2P_CONFIG_TEMPLATES" env-delim:"," description:"Directory of templates overriding the built-in ones. Can be given multiple times; later directories take precedence."`
+
+	AllowMissingKeys bool `long:"allow-missing-keys" env:"P2P_ALLOW_MISSING_KEYS" description:"Render a missing map key as '<no value>' rather than failing, for templates which refer to optional keys directly."`
+
+	ValidateTemplates bool `long:"validate-templates" env:"P2P_VALIDATE_TEMPLATES" description:"Render each template against sample values and report whether it passes, without converting anything."`
+
+	Clean bool `long:"clean" env:"P2P_CLEAN" description:"Remove everything in the project's pipelines, tasks, resources, resource-types, and images directories before converting."`
+
+	PrintTree bool `long:"print-tree" env:"P2P_PRINT_TREE" description:"Print a tree of the generated files once converted."`
+
+	Manifest string `long:"manifest" value-name:"PATH" env:"P2P_MANIFEST" description:"Write a JSON index of every generated file to the given path."`
+
+	Stats bool `long:"stats" env:"P2P_STATS" description:"Print counts of what was converted, e.g. jobs, steps by type, and lines of YAML before and after, and include them in the --manifest."`
+
+	Trace bool `long:"trace" env:"P2P_TRACE" description:"Print how long each phase of the conversion took, e.g. converting each job's tasks, and the files slowest to render, slowest first, and include them in the --manifest."`
+
+	Graph string `long:"graph" value-name:"PATH" env:"P2P_GRAPH" description:"Write a graph of the jobs, their passed constraints, and the resources they get and put to the given path, in Mermaid if it ends in .mmd or .mermaid and Graphviz DOT otherwise."`
+
+	IndexTemplate File   `long:"index-template" value-name:"PATH" env:"P2P_INDEX_TEMPLATE" description:"Template to render an index of the generated files with, e.g. a kustomization.yaml. It's given the same files as --manifest."`
+	IndexOutput   string `long:"index-output" value-name:"PATH" env:"P2P_INDEX_OUTPUT" description:"Path within the project to write the rendered --index-template to."`
+
+	EmitIndex string `long:"emit-index" value-name:"PATH" env:"P2P_EMIT_INDEX" description:"Path within the project to write a Markdown overview of the pipelines, resources, and tasks to, e.g. README.generated.md."`
+
+	NoWrite bool `long:"no-write" env:"P2P_NO_WRITE" description:"Convert the pipeline in memory, loading its tasks and scripts, but write nothing, only failing if it couldn't be converted. --project-path isn't required."`
+
+	Doctor bool `long:"doctor" env:"P2P_DOCTOR" description:"Check that the templates parse, the pipelines parse, each task artifact can be read and has the task files referred to, and the project path is writable, without converting anything."`
+
+	PrettyPrintOnly bool `long:"pretty-print-only" env:"P2P_PRETTY_PRINT_ONLY" description:"Re-render the files already in the project through the templates, e.g. after changing them, rather than converting a pipeline. Only --project-path is required."`
+
+	LogFormat string `long:"log-format" choice:"text" choice:"json" default:"text" env:"P2P_LOG_FORMAT" description:"Format to log in. With 'json', each line is a JSON object, for ingesting into log pipelines."`
+
+	PrintConfig bool `long:"print-config" env:"P2P_PRINT_CONFIG" description:"Print the options in effect as YAML, noting whether each came from a flag, environment variable, config file, or default, and check that the paths they refer to exist. Nothing is converted."`
+
+	MergeResources string `long:"merge-resources" optional:"true" optional-value:"conflict" choice:"conflict" choice:"ours" choice:"theirs" env:"P2P_MERGE_RESOURCES" description:"Keep resource and resource type files which are already in the project when they configure the same thing, e.g. from converting another pipeline. Those which differ are a conflict, shown field by field, unless 'ours' keeps the existing file or 'theirs' replaces it."`
+
+	IgnoreLineEndings bool `long:"ignore-line-endings" env:"P2P_IGNORE_LINE_ENDINGS" description:"Keep files in the project which only differ from what would be generated in their line endings, rather than treating them as conflicts."`
+
+	DiffContext int `long:"diff-context" value-name:"N" env:"P2P_DIFF_CONTEXT" description:"Only show N lines around each change when a file in the project has different content, rather than the whole file."`
+
+	Interactive bool `long:"interactive" env:"P2P_INTERACTIVE" description:"When a file in the project has been changed, show the diff and ask whether to overwrite it, skip it, or abort, rather than aborting. Only applies when stdin is a terminal."`
+
+	Version bool `long:"version" env:"P2P_VERSION" description:"Print the version of pipe2proj and exit."`
+
+	CompletionScript string `long:"completion-script" choice:"bash" choice:"zsh" choice:"fish" hidden:"true" env:"P2P_COMPLETION_SCRIPT" description:"Print a script which sets up completion for the given shell."`
+
+	FailOnWarning bool `long:"fail-on-warning" env:"P2P_FAIL_ON_WARNING" description:"Fail if any warning is logged during the conversion, e.g. about unused resources or tasks which weren't converted, after converting as usual."`
+
+	Watch bool `long:"watch" env:"P2P_WATCH" description:"Keep running, converting again whenever the pipeline config, a vars file, or a task artifact changes."`
+
+	// whether the project has been cleaned during the current conversion
+	cleaned bool
+
+	// decides what to do about conflicting files; conflicts abort if nil
+	resolve resolver
+
+	// what --merge-resources did about each file during the current
+	// conversion
+	merges []mergeDecision
+
+	// counts the warnings logged during the current conversion, with
+	// --fail-on-warning
+	warnings *warningCounter
+
+	// errors for the pipelines --fetch-team couldn't fetch
+	fetchFailures []error
+
+	written []pipe2proj.GeneratedFile
+
+	// the content last written to each file by a conversion in this process,
+	// which may be replaced so long as it hasn't been modified since
+	previous map[string][]byte
+}
+
+func (cmd *Command) Execute([]string) error {
+	if cmd.Version {
+		fmt.Println("pipe2proj", versionString())
+		return nil
+	}
+
+	if cmd.CompletionScript != "" {
+		fmt.Print(completionScripts[cmd.CompletionScript])
+		return nil
+	}
+
+	// main cancels the Context when the process is interrupted, so that files
+	// stop being written
+	if cmd.Context == nil {
+		cmd.Context = context.Background()
+	}
+
+	logrus.SetLevel(logrus.DebugLevel)
+
+	if cmd.LogFormat == "json" {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	}
+
+	if cmd.FailOnWarning {
+		cmd.warnings = &warningCounter{}
+		logrus.AddHook(cmd.warnings)
+	}
+
+	if cmd.YAMLIndent != 0 && (cmd.YAMLIndent < 2 || cmd.YAMLIndent > 9) {
+		return fmt.Errorf("--yaml-indent must be between 2 and 9")
+	}
+
+	if cmd.ExternalizeRegistryCredentials && !cmd.ExtractImages {
+		return fmt.Errorf("--externalize-registry-credentials requires --extract-images")
+	}
+
+	if cmd.IconsFile != "" && !cmd.AutoIcons {
+		return fmt.Errorf("--icons requires --auto-icons")
+	}
+
+	if cmd.TemplateData.Value != nil && !cmd.TemplateContext {
+		return fmt.Errorf("--template-data requires --template-context")
+	}
+
+	if cmd.DiffContext < 0 {
+		return fmt.Errorf("--diff-context must not be negative")
+	}
+
+	if cmd.MaxErrors < 0 {
+		return fmt.Errorf("--max-errors must not be negative")
+	}
+
+	if cmd.SortJobs && cmd.SortOutput == "deps" {
+		return fmt.Errorf("--sort-jobs cannot be used with --sort-output=deps")
+	}
+
+	if cmd.NoTemplates && len(cmd.ConfigTemplates) > 0 {
+		return fmt.Errorf("--no-templates cannot be used with --config-templates")
+	}
+
+	if (cmd.IndexTemplate == "") != (cmd.IndexOutput == "") {
+		return fmt.Errorf("--index-template and --index-output must be given together")
+	}
+
+	if cmd.FromPipeline != "" {
+		if cmd.FetchTeam != "" {
+			return fmt.Errorf("--from-pipeline cannot be used with --fetch-team")
+		}
+
+		if cmd.Target == "" {
+			return fmt.Errorf("--from-pipeline requires --target")
+		}
+
+		if len(cmd.PipelineConfigs) > 0 {
+			return fmt.Errorf("--from-pipeline cannot be used with --pipeline-config")
+		}
+
+		if cmd.PipelinesDir != "" {
+			return fmt.Errorf("--from-pipeline cannot be used with --pipelines-dir")
+		}
+
+		if cmd.Watch {
+			return fmt.Errorf("--from-pipeline cannot be used with --watch")
+		}
+	} else if cmd.FetchTeam != "" {
+		if cmd.Target == "" {
+			return fmt.Errorf("--fetch-team requires --target")
+		}
+
+		if cmd.Team != "" {
+			return fmt.Errorf("--fetch-team cannot be used with --team")
+		}
+
+		if len(cmd.PipelineConfigs) > 0 || cmd.PipelinesDir != "" {
+			return fmt.Errorf("--fetch-team cannot be used with --pipeline-config or --pipelines-dir")
+		}
+
+		if cmd.PipelineName != "" {
+			return fmt.Errorf("--fetch-team cannot be used with --pipeline-name; each pipeline keeps its name")
+		}
+
+		if cmd.Watch {
+			return fmt.Errorf("--fetch-team cannot be used with --watch")
+		}
+
+		cmd.KeepGoing = true
+	} else if cmd.Target != "" {
+		return fmt.Errorf("--target is only used with --from-pipeline or --fetch-team")
+	} else if cmd.Team != "" && cmd.ValidateAgainst == "" {
+		return fmt.Errorf("--team is only used with --from-pipeline, --fetch-team, or --validate-against")
+	}
+
+	if (cmd.SkipPaused || cmd.SkipArchived) && cmd.FetchTeam == "" {
+		return fmt.Errorf("--skip-paused and --skip-archived are only used with --fetch-team")
+	}
+
+	if cmd.PipelinesDir != "" {
+		if len(cmd.PipelineConfigs) > 0 {
+			return fmt.Errorf("--pipelines-dir cannot be used with --pipeline-config")
+		}
+
+		if cmd.PipelineName != "" {
+			return fmt.Errorf("--pipelines-dir cannot be used with --pipeline-name; each pipeline is named after its file")
+		}
+	}
+
+	if cmd.ValidateTemplates {
+		opts := cmd.Options
+
+		var err error
+		opts.Templates, err = cmd.loadTemplates()
+		if err != nil {
+			return fmt.Errorf("loading templates: %s", err)
+		}
+
+		return pipe2proj.ValidateTemplates(os.Stdout, opts)
+	}
+
+	if cmd.Doctor {
+		return cmd.doctor(os.Stdout)
+	}
+
+	if cmd.NoWrite {
+		err := cmd.checkNoWriteFlags()
+		if err != nil {
+			return err
+		}
+
+		// only what's wrong is of interest
+		logrus.SetLevel(logrus.WarnLevel)
+	}
+
+	if cmd.PrettyPrintOnly {
+		if cmd.ProjectPath == "" {
+			return fmt.Errorf("the required flag `-j, --project-path' was not specified")
+		}
+
+		return cmd.prettyPrint()
+	}
+
+	if cmd.PipelineName == "" {
+		err := cmd.derivePipelineName()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := cmd.requireConversionFlags()
+	if err != nil {
+		return err
+	}
+
+	if cmd.Interactive {
+		if isTerminal(os.Stdin) {
+			cmd.resolve = promptResolver(os.Stdin, os.Stderr)
+		} else {
+			logrus.Warn("stdin is not a terminal; conflicts will abort")
+		}
+	}
+
+	if cmd.Watch {
+		return cmd.watch()
+	}
+
+	return cmd.convert()
+}
+
+// derivePipelineName defaults the pipeline name to the name of the pipeline
+// being fetched, or else the config's file name without its extension. There's
+// no default for several configs being merged.
+func (cmd *Command) derivePipelineName() error {
+	var name string
+	switch {
+	case cmd.FromPipeline != "":
+		name = cmd.FromPipeline
+	case len(cmd.PipelineConfigs) > 1:
+		return fmt.Errorf("--pipeline-name is required when merging several pipelines")
+	case len(cmd.PipelineConfigs) == 1:
+		base := filepath.Base(cmd.PipelineConfigs[0].Path())
+		name = strings.TrimSuffix(base, filepath.Ext(base))
+	default:
+		return nil
+	}
+
+	if !identifierRegexp.MatchString(name) {
+		return fmt.Errorf("cannot derive a pipeline name from '%s'; pass --pipeline-name", name)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"name": name,
+	}).Info("derived pipeline name")
+
+	cmd.PipelineName = name
+
+	return nil
+}
+
+// checkNoWriteFlags checks for flags which would write or print something,
+// which --no-write can't be used with.
+func (cmd *Command) checkNoWriteFlags() error {
+	var conflicting []string
+	for flag, given := range map[string]bool{
+		"--clean":            cmd.Clean,
+		"--emit-index":       cmd.EmitIndex != "",
+		"--graph":            cmd.Graph != "",
+		"--index-template":   cmd.IndexTemplate != "",
+		"--interactive":      cmd.Interactive,
+		"--manifest":         cmd.Manifest != "",
+		"--print-tree":       cmd.PrintTree,
+		"--stats":            cmd.Stats,
+		"--trace":            cmd.Trace,
+		"--validate-against": cmd.ValidateAgainst != "",
+		"--watch":            cmd.Watch,
+	} {
+		if given {
+			conflicting = append(conflicting, flag)
+		}
+	}
+
+	if len(conflicting) == 0 {
+		return nil
+	}
+
+	sort.Strings(conflicting)
+
+	return fmt.Errorf("--no-write cannot be used with %s", strings.Join(conflicting, ", "))
+}
+
+// requireConversionFlags checks for the flags needed to convert a pipeline.
+// They aren't marked as required so that e.g. --validate-templates can go
+// without them.
+func (cmd *Command) requireConversionFlags() error {
+	var missing []string
+	if cmd.ProjectName == "" {
+		missing = append(missing, "`-n, --project-name'")
+	}
+
+	if cmd.ProjectPath == "" && !cmd.NoWrite {
+		missing = append(missing, "`-j, --project-path'")
+	}
+
+	if cmd.PipelineName == "" && cmd.PipelinesDir == "" && cmd.FetchTeam == "" {
+		missing = append(missing, "`-p, --pipeline-name'")
+	}
+
+	if len(cmd.PipelineConfigs) == 0 && cmd.FromPipeline == "" && cmd.PipelinesDir == "" && cmd.FetchTeam == "" {
+		missing = append(missing, "`-c, --pipeline-config'")
+	}
+
+	switch len(missing) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("the required flag %s was not specified", missing[0])
+	default:
+		return fmt.Errorf("the required flags %s were not specified", strings.Join(missing, ", "))
+	}
+}
+
+func (cmd *Command) convert() error {
+	if cmd.warnings != nil {
+		cmd.warnings.reset()
+	}
+
+	opts, cleanup, err := cmd.options()
+	if err != nil {
+		return err
+	}
+
+	defer cleanup()
+
+	// e.g. while fetching pipelines
+	if err := cmd.interrupted(); err != nil {
+		return err
+	}
+
+	if cmd.previous == nil {
+		cmd.previous = map[string][]byte{}
+	}
+
+	for _, file := range cmd.written {
+		cmd.previous[file.Path] = file.Payload
+	}
+
+	cmd.written = nil
+	cmd.cleaned = false
+	cmd.merges = nil
+
+	// summarize the merges even if the conversion fails part way through
+	defer cmd.logMerges()
+
+	if cmd.FetchTeam != "" && len(opts.Pipelines) == 0 {
+		return pipe2proj.PipelineErrors{
+			Errors: cmd.fetchFailures,
+			Total:  len(cmd.fetchFailures),
+			Max:    cmd.MaxErrors,
+		}
+	}
+
+	started := time.Now()
+	result, convertErr := pipe2proj.Convert(opts)
+	took := time.Since(started)
+
+	if cmd.NoWrite {
+		return convertErr
+	}
+
+	// the error from the write which noticed may have been wrapped, or
+	// collected with --keep-going
+	if err := cmd.interrupted(); err != nil {
+		return err
+	}
+
+	if result == nil {
+		return convertErr
+	}
+
+	if len(cmd.fetchFailures) > 0 {
+		convertErr = withFetchFailures(convertErr, cmd.fetchFailures, len(opts.Pipelines), cmd.MaxErrors)
+	}
+
+	// with --keep-going, the pipelines which converted are still indexed
+	files := result.Files
+
+	if cmd.IndexTemplate != "" {
+		payload, err := renderIndex(cmd.IndexTemplate.Path(), files)
+		if err != nil {
+			return fmt.Errorf("rendering index: %s", err)
+		}
+
+		index := pipe2proj.GeneratedFile{
+			Path:    filepath.Clean(cmd.IndexOutput),
+			Kind:    "index",
+			Source:  cmd.IndexTemplate.Path(),
+			Payload: payload,
+			Mode:    0644,
+		}
+
+		err = cmd.WriteFile(index)
+		if err != nil {
+			return fmt.Errorf("failed to write index: %w", err)
+		}
+
+		files = append(files, index)
+	}
+
+	if cmd.EmitIndex != "" {
+		buf := new(bytes.Buffer)
+		err := result.Overview.WriteMarkdown(buf)
+		if err != nil {
+			return fmt.Errorf("rendering overview: %s", err)
+		}
+
+		overview := pipe2proj.GeneratedFile{
+			Path:    filepath.Clean(cmd.EmitIndex),
+			Kind:    "overview",
+			Payload: buf.Bytes(),
+			Mode:    0644,
+		}
+
+		err = cmd.WriteFile(overview)
+		if err != nil {
+			return fmt.Errorf("failed to write overview: %w", err)
+		}
+
+		files = append(files, overview)
+	}
+
+	var stats *pipe2proj.Stats
+	if cmd.Stats {
+		stats = &result.Stats
+	}
+
+	var trace *pipe2proj.Trace
+	if cmd.Trace {
+		trace = &result.Trace
+	}
+
+	if cmd.Manifest != "" {
+		err = writeManifest(cmd.Manifest, files, stats, trace)
+		if err != nil {
+			return fmt.Errorf("failed to write manifest: %s", err)
+		}
+	}
+
+	if cmd.Graph != "" {
+		err = writeGraph(cmd.Graph, result.Graph)
+		if err != nil {
+			return fmt.Errorf("failed to write graph: %s", err)
+		}
+	}
+
+	if cmd.PrintTree {
+		printTree(os.Stdout, cmd.ProjectPath.Path(), files)
+	}
+
+	if cmd.Stats {
+		err := printStats(os.Stdout, result.Stats)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cmd.Trace {
+		err := printTrace(os.Stdout, took, result.Trace)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cmd.ValidateAgainst != "" {
+		team := cmd.Team
+		if cmd.FetchTeam != "" {
+			team = cmd.FetchTeam
+		}
+
+		err := validateAgainst(cmd.Context, os.Stdout, cmd.ValidateAgainst, team, result.Pipelines)
+		if err != nil {
+			if convertErr != nil {
+				// the conversion failing matters more
+				logrus.WithError(err).Error("failed to validate against target")
+				return convertErr
+			}
+
+			return err
+		}
+	}
+
+	if convertErr == nil && cmd.warnings != nil {
+		return cmd.warnings.check()
+	}
+
+	return convertErr
+}
+
+// withFetchFailures adds the pipelines which couldn't be fetched to those
+// which failed to convert, if any.
+func withFetchFailures(err error, fetchFailures []error, fetched int, max int) error {
+	failed := pipe2proj.PipelineErrors{
+		Errors: fetchFailures,
+		Total:  len(fetchFailures) + fetched,
+		Max:    max,
+	}
+
+	var convertFailures pipe2proj.PipelineErrors
+	if errors.As(err, &convertFailures) {
+		failed.Errors = append(failed.Errors, convertFailures.Errors...)
+	} else if err != nil {
+		failed.Errors = append(failed.Errors, err)
+	}
+
+	return failed
+}
+
+// prettyPrint re-renders the project's files in place.
+func (cmd *Command) prettyPrint() error {
+	opts := cmd.Options
+
+	var err error
+	opts.Templates, err = cmd.loadTemplates()
+	if err != nil {
+		return fmt.Errorf("loading templates: %s", err)
+	}
+
+	opts.Writer = cmd
+
+	result, err := pipe2proj.PrettyPrint(os.DirFS(cmd.ProjectPath.Path()), opts)
+	if err != nil {
+		return err
+	}
+
+	if cmd.PrintTree {
+		printTree(os.Stdout, cmd.ProjectPath.Path(), result.Files)
+	}
+
+	return nil
+}
+
+// options fills in the conversion options which come from the filesystem.
+// The returned func removes any tarballs extracted along the way.
+func (cmd *Command) options() (pipe2proj.Options, func(), error) {
+	opts := cmd.Options
+
+	var extracted []string
+	cleanup := func() {
+		for _, dir := range extracted {
+			_ = os.RemoveAll(dir)
+		}
+	}
+
+	var err error
+	opts.Templates, err = cmd.loadTemplates()
+	if err != nil {
+		return opts, cleanup, fmt.Errorf("loading templates: %s", err)
+	}
+
+	err = cmd.loadConfigs(&opts)
+	if err != nil {
+		return opts, cleanup, err
+	}
+
+	opts.Vars, err = loadVars(cmd.VarsFiles)
+	if err != nil {
+		return opts, cleanup, fmt.Errorf("loading vars: %s", err)
+	}
+
+	if cmd.IconsFile != "" {
+		opts.Icons, err = loadIcons(cmd.IconsFile)
+		if err != nil {
+			return opts, cleanup, fmt.Errorf("loading icons: %s", err)
+		}
+	}
+
+	opts.TaskArtifacts = map[string]fs.FS{}
+	for name, artifact := range cmd.TaskResources {
+		dir := artifact.Path()
+
+		if artifact.IsTarball() && cmd.NoWrite {
+			opts.TaskArtifacts[name], err = loadTarball(artifact.Path())
+			if err != nil {
+				return opts, cleanup, fmt.Errorf("loading task artifact '%s': %s", name, err)
+			}
+
+			continue
+		}
+
+		if artifact.IsTarball() {
+			dir, err = extractTarball(artifact.Path())
+			if err != nil {
+				return opts, cleanup, fmt.Errorf("loading task artifact '%s': %s", name, err)
+			}
+
+			extracted = append(extracted, dir)
+		}
+
+		opts.TaskArtifacts[name] = os.DirFS(dir)
+	}
+
+	if !cmd.NoWrite {
+		opts.Writer = cmd
+	}
+
+	return opts, cleanup, nil
+}
+
+// loadConfigs fills in the pipeline configs to convert, reading or fetching
+// them from wherever the flags say.
+func (cmd *Command) loadConfigs(opts *pipe2proj.Options) error {
+	var err error
+	if cmd.FromPipeline != "" {
+		opts.Config, opts.ConfigSource, err = fetchPipelineConfig(cmd.Context, cmd.Target, cmd.Team, cmd.FromPipeline)
+		if err != nil {
+			return fmt.Errorf("fetching pipeline: %s", err)
+		}
+	} else if cmd.FetchTeam != "" {
+		opts.Pipelines, cmd.fetchFailures, err = cmd.fetchTeamPipelines()
+		if err != nil {
+			return fmt.Errorf("fetching pipelines: %s", err)
+		}
+
+		if len(opts.Pipelines) == 0 && len(cmd.fetchFailures) == 0 {
+			return fmt.Errorf("no pipelines to convert in team '%s'", cmd.FetchTeam)
+		}
+	} else if cmd.PipelinesDir != "" {
+		opts.Pipelines, err = loadPipelines(cmd.PipelinesDir.Path())
+		if err != nil {
+			return fmt.Errorf("loading pipelines: %s", err)
+		}
+	} else if len(cmd.PipelineConfigs) > 1 {
+		for _, file := range cmd.PipelineConfigs {
+			config, err := ioutil.ReadFile(file.Path())
+			if err != nil {
+				return fmt.Errorf("read: %s", err)
+			}
+
+			opts.Pipelines = append(opts.Pipelines, pipe2proj.Pipeline{
+				Name:   cmd.PipelineName,
+				Config: config,
+				Source: file.Path(),
+			})
+		}
+
+		opts.MergePipelines = true
+	} else {
+		opts.Config, err = ioutil.ReadFile(cmd.PipelineConfigs[0].Path())
+		if err != nil {
+			return fmt.Errorf("read: %s", err)
+		}
+
+		opts.ConfigSource = cmd.PipelineConfigs[0].Path()
+	}
+
+	return nil
+}
+
+// WriteFile writes the file into the project, cleaning the project first if
+// --clean was given.
+func (cmd *Command) WriteFile(file pipe2proj.GeneratedFile) error {
+	// a file being written when interrupted is finished, so none are left
+	// half-written, but no more are started
+	if err := cmd.interrupted(); err != nil {
+		return err
+	}
+
+	if cmd.Clean && !cmd.cleaned {
+		err := cmd.clean()
+		if err != nil {
+			return fmt.Errorf("failed to clean: %s", err)
+		}
+
+		cmd.cleaned = true
+	}
+
+	dest := filepath.Join(cmd.ProjectPath.Path(), file.Path)
+
+	// files being pretty-printed are replaced in place
+	replace := cmd.PrettyPrintOnly
+
+	if previous, found := cmd.previous[file.Path]; found {
+		existing, err := ioutil.ReadFile(dest)
+		if err == nil && bytes.Equal(existing, previous) {
+			// we wrote this file last time; let the new content replace it
+			replace = true
+		}
+	}
+
+	if replace {
+		err := os.Remove(dest)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else if cmd.MergeResources != "" && isMergeable(file) {
+		keep, err := cmd.mergeResource(dest, file)
+		if err != nil {
+			return err
+		}
+
+		if keep {
+			return nil
+		}
+	}
+
+	err := syncFile(dest, file.Payload, file.Mode, cmd.DiffContext, cmd.IgnoreLineEndings, cmd.resolve)
+	if err != nil {
+		return err
+	}
+
+	for _, written := range cmd.written {
+		if written.Path == file.Path {
+			return nil
+		}
+	}
+
+	cmd.written = append(cmd.written, file)
+
+	return nil
+}
+
+// clean removes everything from the project's generated directories.
+func (cmd *Command) clean() error {
+	for _, dir := range []string{"pipelines", "tasks", "resources", "resource-types", "images"} {
+		logrus.WithFields(logrus.Fields{
+			"dir": dir,
+		}).Info("cleaning")
+
+		err := os.RemoveAll(filepath.Join(cmd.ProjectPath.Path(), dir))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// interrupted returns an InterruptedError listing the files written during
+// the current conversion, if the process has been interrupted.
+func (cmd *Command) interrupted() error {
+	if cmd.Context.Err() == nil {
+		return nil
+	}
+
+	var written []string
+	for _, file := range cmd.written {
+		written = append(written, file.Path)
+	}
+
+	return InterruptedError{
+		Written: written,
+	}
+}
+
+// toLF ends each line of the payload with '\n' rather than '\r\n'.
+func toLF(payload []byte) []byte {
+	return bytes.ReplaceAll(payload, []byte("\r\n"), []byte("\n"))
+}
+
+// syncFile writes the payload to the path. If the path already has different
+// content, the resolver decides what to do about it, if given; otherwise it
+// returns a ConflictError.
+func syncFile(path string, payload []byte, mode os.FileMode, diffContext int, ignoreLineEndings bool, resolve resolver) error {
+	parent := filepath.Dir(path)
+	if _, err := os.Stat(parent); os.IsNotExist(err) {
+		err = os.MkdirAll(parent, 0755)
+		if err != nil {
+			return err
+		}
+	}
+
+	existingPayload, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+	} else if ignoreLineEndings && !bytes.Equal(existingPayload, payload) && bytes.Equal(toLF(existingPayload), toLF(payload)) {
+		logrus.WithFields(logrus.Fields{
+			"path": path,
+		}).Info("keeping file which only differs in line endings")
+
+		return nil
+	} else {
+		dmp := diffmatchpatch.New()
+
+		diffs := dmp.DiffMain(string(existingPayload), string(payload), true)
+		if diffContext > 0 {
+			diffs = limitDiffContext(diffs, diffContext)
+		}
+
+		if !bytes.Equal(existingPayload, payload) {
+			conflict := ConflictError{
+				Path: path,
+				Diff: dmp.DiffPrettyText(diffs),
+			}
+
+			if resolve == nil {
+				return conflict
+			}
+
+			resolution, err := resolve(conflict)
+			if err != nil {
+				return err
+			}
+
+			switch resolution {
+			case resolveOverwrite:
+				logrus.WithFields(logrus.Fields{
+					"path": path,
+				}).Info("overwriting")
+			case resolveSkip:
+				logrus.WithFields(logrus.Fields{
+					"path": path,
+				}).Info("skipping")
+
+				return nil
+			default:
+				return conflict
+			}
+		}
+	}
+
+	err = ioutil.WriteFile(path, payload, mode)
+	if err != nil {
+		return fmt.Errorf("failed to write file: %s", err)
+	}
+
+	err = os.Chmod(path, mode)
+	if err != nil {
+		return fmt.Errorf("failed to chmod file: %s", err)
+	}
+
+	return nil
+}
+
+func failIf(msg string, err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msg, err)
+		fmt.Fprintln(os.Stderr)
+		os.Exit(exitCode(err))
+	}
+}
+
+func main() {
+	var cmd Command
+	parser := newParser(&cmd)
+
+	var configured map[*flags.Option]bool
+
+	configPath := configFilePath(os.Args[1:])
+	if configPath != "" {
+		var err error
+		configured, err = loadConfigFile(parser, configPath)
+		failIf("config: %s", err)
+	}
+
+	args, err := parser.Parse()
+	failIf("parse: %s", err)
+
+	if cmd.PrintConfig {
+		err = printConfig(os.Stdout, parser, configPath, configured)
+		failIf("error: %s", err)
+		return
+	}
+
+	var stop context.CancelFunc
+	cmd.Context, stop = signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// only the first interrupt stops cleanly; a second kills the process as
+	// usual, e.g. if a step filter is stuck
+	go func() {
+		<-cmd.Context.Done()
+		stop()
+	}()
+
+	err = cmd.Execute(args)
+	failIf("error: %s", err)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/vito/pipe2proj"
+)
+
+type Manifest struct {
+	// the version of pipe2proj which generated the files
+	Version string `json:"version"`
+
+	Files []ManifestFile `json:"files"`
+
+	// what was converted, with --stats
+	Stats *pipe2proj.Stats `json:"stats,omitempty"`
+
+	// how long the conversion took, with --trace
+	Trace *pipe2proj.Trace `json:"trace,omitempty"`
+}
+
+type ManifestFile struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	Source string `json:"source,omitempty"`
+
+	// the pipelines the file was generated for
+	Pipelines []string `json:"pipelines,omitempty"`
+
+	SHA256 string `json:"sha256"`
+}
+
+func newManifest(files []pipe2proj.GeneratedFile) Manifest {
+	manifest := Manifest{
+		Version: versionString(),
+		Files:   []ManifestFile{},
+	}
+
+	for _, file := range files {
+		manifest.Files = append(manifest.Files, ManifestFile{
+			Path:      file.Path,
+			Kind:      file.Kind,
+			Source:    file.Source,
+			Pipelines: file.Pipelines,
+			SHA256:    fmt.Sprintf("%x", sha256.Sum256(file.Payload)),
+		})
+	}
+
+	return manifest
+}
+
+func writeManifest(path string, files []pipe2proj.GeneratedFile, stats *pipe2proj.Stats, trace *pipe2proj.Trace) error {
+	manifest := newManifest(files)
+	manifest.Stats = stats
+	manifest.Trace = trace
+
+	payload, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(payload, '\n'), 0644)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/pipe2proj"
+	"gopkg.in/yaml.v2"
+)
+
+// what --merge-resources decided to do about a file
+const (
+	mergeEquivalent = "kept existing; equivalent"
+	mergeOurs       = "kept existing; ours"
+	mergeTheirs     = "replaced; theirs"
+	mergeOverwrite  = "replaced; overwritten"
+	mergeSkip       = "kept existing; skipped"
+)
+
+// mergeDecision records what was done about a resource file which already
+// existed with different content.
+type mergeDecision struct {
+	Path     string
+	Decision string
+}
+
+// isMergeable reports whether --merge-resources applies to the file.
+func isMergeable(file pipe2proj.GeneratedFile) bool {
+	return file.Kind == "resource" || file.Kind == "resource-type"
+}
+
+// mergeResource decides what to do about a resource or resource type file
+// which already exists with different content, comparing the two by what
+// they configure rather than how they're written. It returns whether the
+// existing file should be kept.
+func (cmd *Command) mergeResource(dest string, file pipe2proj.GeneratedFile) (bool, error) {
+	existing, err := ioutil.ReadFile(dest)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	if bytes.Equal(existing, file.Payload) {
+		return false, nil
+	}
+
+	existingValue, err := normalizeResource(existing)
+	if err != nil {
+		// leave it to the usual conflict handling
+		return false, nil
+	}
+
+	newValue, err := normalizeResource(file.Payload)
+	if err != nil {
+		return false, err
+	}
+
+	changes := fieldDiff("", existingValue, newValue)
+
+	var decision string
+	switch {
+	case len(changes) == 0:
+		decision = mergeEquivalent
+	case cmd.MergeResources == "ours":
+		decision = mergeOurs
+	case cmd.MergeResources == "theirs":
+		decision = mergeTheirs
+	default:
+		conflict := ConflictError{
+			Path: dest,
+			Diff: strings.Join(changes, "\n"),
+		}
+
+		if cmd.resolve == nil {
+			return false, conflict
+		}
+
+		resolution, err := cmd.resolve(conflict)
+		if err != nil {
+			return false, err
+		}
+
+		switch resolution {
+		case resolveOverwrite:
+			decision = mergeOverwrite
+		case resolveSkip:
+			decision = mergeSkip
+		default:
+			return false, conflict
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"path":     file.Path,
+		"decision": decision,
+		"changes":  changes,
+	}).Info("merging")
+
+	cmd.merges = append(cmd.merges, mergeDecision{
+		Path:     file.Path,
+		Decision: decision,
+	})
+
+	keep := decision == mergeEquivalent || decision == mergeOurs || decision == mergeSkip
+	if !keep {
+		err := os.Remove(dest)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return keep, nil
+}
+
+// logMerges summarizes what --merge-resources decided for each file.
+func (cmd *Command) logMerges() {
+	for _, merge := range cmd.merges {
+		logrus.WithFields(logrus.Fields{
+			"path":     merge.Path,
+			"decision": merge.Decision,
+		}).Info("merged")
+	}
+}
+
+// normalizeResource decodes a resource or resource type file into a generic
+// value, leaving out what doesn't change how it's configured: key order,
+// formatting, its name, and a check_every of Concourse's default.
+func normalizeResource(payload []byte) (interface{}, error) {
+	var anon pipe2proj.AnonymousResourceConfig
+	err := yaml.UnmarshalStrict(payload, &anon)
+	if err != nil {
+		return nil, err
+	}
+
+	anon.Name = ""
+
+	if anon.CheckEvery == "1m" {
+		anon.CheckEvery = ""
+	}
+
+	normalized, err := yaml.Marshal(anon)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	err = yaml.Unmarshal(normalized, &value)
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// fieldDiff describes each field which differs between the two values, e.g.
+// 'source.branch: "main" -> "dev"'. Mappings are compared key by key, and
+// anything else as a whole.
+func fieldDiff(path string, a interface{}, b interface{}) []string {
+	aMap, aIsMap := a.(map[interface{}]interface{})
+	bMap, bIsMap := b.(map[interface{}]interface{})
+
+	if aIsMap && bIsMap {
+		keys := map[string]interface{}{}
+		for key := range aMap {
+			keys[fmt.Sprint(key)] = key
+		}
+
+		for key := range bMap {
+			keys[fmt.Sprint(key)] = key
+		}
+
+		var names []string
+		for name := range keys {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		var changes []string
+		for _, name := range names {
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+
+			changes = append(changes, fieldDiff(fieldPath, aMap[keys[name]], bMap[keys[name]])...)
+		}
+
+		return changes
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("%s: %s -> %s", path, describeValue(a), describeValue(b))}
+}
+
+func describeValue(value interface{}) string {
+	if value == nil {
+		return "(unset)"
+	}
+
+	payload, err := json.Marshal(jsonValue(value))
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(payload)
+}
+
+// jsonValue converts YAML mappings into something JSON can marshal.
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		obj := map[string]interface{}{}
+		for key, val := range v {
+			obj[fmt.Sprint(key)] = jsonValue(val)
+		}
+
+		return obj
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, val := range v {
+			list[i] = jsonValue(val)
+		}
+
+		return list
+	default:
+		return v
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// File is a flag value naming an existing file. It completes to file paths.
+type File string
+
+func (f *File) UnmarshalFlag(value string) error {
+	stat, err := os.Stat(value)
+	if err != nil {
+		return err
+	}
+
+	if stat.IsDir() {
+		return fmt.Errorf("path '%s' is a directory, not a file", value)
+	}
+
+	abs, err := filepath.Abs(value)
+	if err != nil {
+		return err
+	}
+
+	*f = File(abs)
+
+	return nil
+}
+
+func (f File) Path() string {
+	return string(f)
+}
+
+func (File) Complete(match string) []flags.Completion {
+	return completePaths(match, false)
+}
+
+// Dir is a flag value naming a directory, which needn't exist yet. It
+// completes to directory paths.
+type Dir string
+
+func (f *Dir) UnmarshalFlag(value string) error {
+	stat, err := os.Stat(value)
+	if err == nil && !stat.IsDir() {
+		return fmt.Errorf("path '%s' is not a directory", value)
+	}
+
+	abs, err := filepath.Abs(value)
+	if err != nil {
+		return err
+	}
+
+	*f = Dir(abs)
+
+	return nil
+}
+
+func (f Dir) Path() string {
+	return string(f)
+}
+
+func (Dir) Complete(match string) []flags.Completion {
+	return completePaths(match, true)
+}
+
+// completePaths completes the partial path, suffixing directories with a
+// slash so that completion can continue into them.
+func completePaths(match string, dirsOnly bool) []flags.Completion {
+	paths, _ := filepath.Glob(match + "*")
+
+	var completions []flags.Completion
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		if info.IsDir() {
+			path += string(filepath.Separator)
+		} else if dirsOnly {
+			continue
+		}
+
+		completions = append(completions, flags.Completion{Item: path})
+	}
+
+	return completions
+}
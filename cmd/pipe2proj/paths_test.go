@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// completionTree creates a dir to complete paths in, returning it with a
+// trailing separator.
+func completionTree(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for _, sub := range []string{"ci", "tasks"} {
+		err := os.Mkdir(filepath.Join(dir, sub), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, file := range []string{"ci.yml", "pipeline.yml", filepath.Join("tasks", "unit.yml")} {
+		err := os.WriteFile(filepath.Join(dir, file), nil, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir + string(filepath.Separator)
+}
+
+func completionItems(completions []flags.Completion) []string {
+	var items []string
+	for _, completion := range completions {
+		items = append(items, completion.Item)
+	}
+
+	return items
+}
+
+func TestPathCompleters(t *testing.T) {
+	dir := completionTree(t)
+	sep := string(filepath.Separator)
+
+	for _, test := range []struct {
+		title    string
+		complete func(string) []flags.Completion
+		match    string
+		expected []string
+	}{
+		{
+			title:    "files in a dir",
+			complete: File("").Complete,
+			match:    dir,
+			expected: []string{dir + "ci" + sep, dir + "ci.yml", dir + "pipeline.yml", dir + "tasks" + sep},
+		},
+		{
+			title:    "files with a prefix",
+			complete: File("").Complete,
+			match:    dir + "ci",
+			expected: []string{dir + "ci" + sep, dir + "ci.yml"},
+		},
+		{
+			title:    "files in a subdir",
+			complete: File("").Complete,
+			match:    dir + "tasks" + sep,
+			expected: []string{dir + "tasks" + sep + "unit.yml"},
+		},
+		{
+			title:    "no matching files",
+			complete: File("").Complete,
+			match:    dir + "bogus",
+		},
+		{
+			title:    "dirs in a dir",
+			complete: Dir("").Complete,
+			match:    dir,
+			expected: []string{dir + "ci" + sep, dir + "tasks" + sep},
+		},
+		{
+			title:    "dirs with a prefix",
+			complete: Dir("").Complete,
+			match:    dir + "ci",
+			expected: []string{dir + "ci" + sep},
+		},
+		{
+			title:    "no matching dirs",
+			complete: Dir("").Complete,
+			match:    dir + "pipe",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			items := completionItems(test.complete(test.match))
+			if !reflect.DeepEqual(items, test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, items)
+			}
+		})
+	}
+}
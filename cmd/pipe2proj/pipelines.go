@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vito/pipe2proj"
+)
+
+// isPipelineFile reports whether the file name is one --pipelines-dir
+// converts.
+func isPipelineFile(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == ".yml" || ext == ".yaml"
+}
+
+// loadPipelines reads every pipeline config in the directory, naming each
+// pipeline after its file.
+func loadPipelines(dir string) ([]pipe2proj.Pipeline, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var pipelines []pipe2proj.Pipeline
+	sources := map[string]string{}
+	for _, info := range infos {
+		if info.IsDir() || !isPipelineFile(info.Name()) {
+			continue
+		}
+
+		source := filepath.Join(dir, info.Name())
+		name := strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
+
+		if !identifierRegexp.MatchString(name) {
+			return nil, fmt.Errorf("%s: '%s' is not a valid pipeline name", source, name)
+		}
+
+		if other, found := sources[name]; found {
+			return nil, fmt.Errorf("%s and %s would both be named '%s'", other, source, name)
+		}
+
+		sources[name] = source
+
+		config, err := ioutil.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+
+		pipelines = append(pipelines, pipe2proj.Pipeline{
+			Name:   name,
+			Config: config,
+			Source: source,
+		})
+	}
+
+	if len(pipelines) == 0 {
+		return nil, fmt.Errorf("no *.yml or *.yaml files in %s", dir)
+	}
+
+	sort.Slice(pipelines, func(i, j int) bool {
+		return pipelines[i].Name < pipelines[j].Name
+	})
+
+	return pipelines, nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+	yamlv3 "go.yaml.in/yaml/v3"
+)
+
+// options whose values shouldn't be printed
+var secretOptionRegexp = regexp.MustCompile(`(?i)token|password|secret|credential`)
+
+// printConfig prints each option which has a value as YAML, commented with
+// where the value came from, and then checks that the paths the options
+// refer to exist.
+func printConfig(w io.Writer, parser *flags.Parser, configPath string, configured map[*flags.Option]bool) error {
+	doc := &yamlv3.Node{Kind: yamlv3.MappingNode}
+
+	var missing []string
+	for _, option := range allOptions(parser.Groups()) {
+		if option.LongName == "print-config" {
+			continue
+		}
+
+		var source string
+		switch {
+		case option.IsSet() && !option.IsSetDefault():
+			source = "flag"
+		case !option.IsSetDefault():
+			continue
+		case option.EnvDefaultKey != "" && isEnvSet(option.EnvDefaultKey):
+			source = "env $" + option.EnvDefaultKey
+		case configured[option]:
+			source = "config " + configPath
+		case len(option.Default) > 0:
+			source = "default"
+		default:
+			continue
+		}
+
+		value := &yamlv3.Node{}
+		if secretOptionRegexp.MatchString(option.LongName) {
+			value.SetString("<redacted>")
+		} else {
+			err := value.Encode(flagValue(reflect.ValueOf(option.Value())))
+			if err != nil {
+				return err
+			}
+		}
+
+		key := &yamlv3.Node{}
+		key.SetString(option.LongName)
+		key.LineComment = source
+
+		doc.Content = append(doc.Content, key, value)
+
+		// the project path is created if need be
+		if option.LongName != "project-path" {
+			for _, path := range optionPaths(option.Value()) {
+				if _, err := os.Stat(path); err != nil {
+					missing = append(missing, fmt.Sprintf("--%s: %s", option.LongName, err))
+				}
+			}
+		}
+	}
+
+	enc := yamlv3.NewEncoder(w)
+	enc.SetIndent(2)
+
+	err := enc.Encode(doc)
+	if err != nil {
+		return err
+	}
+
+	err = enc.Close()
+	if err != nil {
+		return err
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing paths:\n  %s", strings.Join(missing, "\n  "))
+	}
+
+	return nil
+}
+
+func allOptions(groups []*flags.Group) []*flags.Option {
+	var options []*flags.Option
+	for _, group := range groups {
+		options = append(options, group.Options()...)
+		options = append(options, allOptions(group.Groups())...)
+	}
+
+	return options
+}
+
+// isEnvSet reports whether the environment variable is set, the same way
+// go-flags does when deciding whether to use it.
+func isEnvSet(key string) bool {
+	_, found := os.LookupEnv(key)
+	return found
+}
+
+// flagValue converts an option's value into something which marshals the
+// way it would be given as a flag.
+func flagValue(value reflect.Value) interface{} {
+	if marshaler, ok := value.Interface().(flags.Marshaler); ok {
+		str, err := marshaler.MarshalFlag()
+		if err == nil {
+			return str
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Slice:
+		list := []interface{}{}
+		for i := 0; i < value.Len(); i++ {
+			list = append(list, flagValue(value.Index(i)))
+		}
+
+		return list
+
+	case reflect.Map:
+		mapping := map[string]interface{}{}
+		for _, key := range value.MapKeys() {
+			mapping[fmt.Sprint(key.Interface())] = flagValue(value.MapIndex(key))
+		}
+
+		return mapping
+
+	case reflect.String:
+		return value.String()
+
+	default:
+		return value.Interface()
+	}
+}
+
+// optionPaths returns the paths an option's value refers to, if any.
+func optionPaths(value interface{}) []string {
+	switch v := value.(type) {
+	case File:
+		return nonEmpty(v.Path())
+	case Dir:
+		return nonEmpty(v.Path())
+	case []File:
+		var paths []string
+		for _, file := range v {
+			paths = append(paths, file.Path())
+		}
+
+		return paths
+	case []Dir:
+		var paths []string
+		for _, dir := range v {
+			paths = append(paths, dir.Path())
+		}
+
+		return paths
+	case TaskArtifacts:
+		var paths []string
+		for _, artifact := range v {
+			paths = append(paths, artifact.Path())
+		}
+
+		sort.Strings(paths)
+
+		return paths
+	default:
+		return nil
+	}
+}
+
+func nonEmpty(path string) []string {
+	if path == "" {
+		return nil
+	}
+
+	return []string{path}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// resolution is what to do about a file which conflicts with what would be
+// generated.
+type resolution int
+
+const (
+	resolveAbort resolution = iota
+	resolveOverwrite
+	resolveSkip
+)
+
+// resolver decides what to do about a conflicting file.
+type resolver func(ConflictError) (resolution, error)
+
+// isTerminal reports whether the file is a terminal rather than e.g. a pipe.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// promptResolver shows each conflict's diff and asks whether to overwrite
+// the file, skip it, or abort.
+func promptResolver(in io.Reader, out io.Writer) resolver {
+	reader := bufio.NewReader(in)
+
+	return func(conflict ConflictError) (resolution, error) {
+		fmt.Fprintf(out, "%s has been changed:\n\n%s\n\n", conflict.Path, conflict.Diff)
+
+		for {
+			fmt.Fprintf(out, "overwrite, skip, or abort? [o/s/a] ")
+
+			line, err := reader.ReadString('\n')
+			if err == io.EOF && line == "" {
+				fmt.Fprintln(out)
+				return resolveAbort, nil
+			}
+
+			if err != nil && err != io.EOF {
+				return resolveAbort, err
+			}
+
+			switch strings.ToLower(strings.TrimSpace(line)) {
+			case "o", "overwrite":
+				return resolveOverwrite, nil
+			case "s", "skip":
+				return resolveSkip, nil
+			case "a", "abort":
+				return resolveAbort, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/vito/pipe2proj"
+)
+
+// printStats prints the stats as a table, with a row for each type of step
+// and resource.
+func printStats(w io.Writer, stats pipe2proj.Stats) error {
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(table, "pipelines\t%d\n", stats.Pipelines)
+	fmt.Fprintf(table, "jobs\t%d\n", stats.Jobs)
+
+	for _, name := range sortedKeys(stats.Steps) {
+		fmt.Fprintf(table, "steps: %s\t%d\n", name, stats.Steps[name])
+	}
+
+	fmt.Fprintf(table, "tasks converted\t%d\n", stats.TasksConverted)
+	fmt.Fprintf(table, "tasks left as files\t%d\n", stats.TaskFiles)
+	fmt.Fprintf(table, "inline tasks\t%d\n", stats.InlineTasks)
+	fmt.Fprintf(table, "scripts copied\t%d\n", stats.ScriptsCopied)
+
+	for _, name := range sortedKeys(stats.Resources) {
+		fmt.Fprintf(table, "resources: %s\t%d\n", name, stats.Resources[name])
+	}
+
+	fmt.Fprintf(table, "lines of YAML before\t%d\n", stats.LinesBefore)
+	fmt.Fprintf(table, "lines of YAML after\t%d\n", stats.LinesAfter)
+
+	return table.Flush()
+}
+
+func sortedKeys(counts map[string]int) []string {
+	var keys []string
+	for key := range counts {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
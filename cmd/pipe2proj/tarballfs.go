@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tarballFS is a tarball's directories and regular files read into memory,
+// keyed by their slash-separated paths, so that a task artifact can be read
+// without extracting it.
+type tarballFS map[string]*tarballFile
+
+// tarballFile is a directory or regular file in a tarballFS.
+type tarballFile struct {
+	data []byte
+	mode fs.FileMode
+}
+
+// add adds the file, along with any of its parent directories the tarball
+// has no entries for.
+func (fsys tarballFS) add(name string, file *tarballFile) {
+	fsys[name] = file
+
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if _, found := fsys[dir]; found {
+			break
+		}
+
+		fsys[dir] = &tarballFile{mode: fs.ModeDir | 0755}
+	}
+}
+
+func (fsys tarballFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	file, found := fsys[name]
+	if name == "." {
+		file, found = &tarballFile{mode: fs.ModeDir | 0755}, true
+	}
+
+	if !found {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	info := tarballInfo{name: path.Base(name), file: file}
+
+	if !file.mode.IsDir() {
+		return &openTarballFile{
+			Reader: bytes.NewReader(file.data),
+			info:   info,
+		}, nil
+	}
+
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+
+	var entries []fs.DirEntry
+	for entryName, entry := range fsys {
+		if !strings.HasPrefix(entryName, prefix) || strings.Contains(entryName[len(prefix):], "/") {
+			continue
+		}
+
+		entries = append(entries, tarballInfo{name: path.Base(entryName), file: entry})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return &openTarballDir{
+		path:    name,
+		info:    info,
+		entries: entries,
+	}, nil
+}
+
+// tarballInfo describes a tarballFile, both as a file and as an entry of its
+// directory.
+type tarballInfo struct {
+	name string
+	file *tarballFile
+}
+
+func (info tarballInfo) Name() string               { return info.name }
+func (info tarballInfo) Size() int64                { return int64(len(info.file.data)) }
+func (info tarballInfo) Mode() fs.FileMode          { return info.file.mode }
+func (info tarballInfo) ModTime() time.Time         { return time.Time{} }
+func (info tarballInfo) IsDir() bool                { return info.file.mode.IsDir() }
+func (info tarballInfo) Sys() interface{}           { return nil }
+func (info tarballInfo) Type() fs.FileMode          { return info.file.mode.Type() }
+func (info tarballInfo) Info() (fs.FileInfo, error) { return info, nil }
+
+// openTarballFile is a regular file opened from a tarballFS.
+type openTarballFile struct {
+	*bytes.Reader
+
+	info tarballInfo
+}
+
+func (file *openTarballFile) Stat() (fs.FileInfo, error) { return file.info, nil }
+func (file *openTarballFile) Close() error               { return nil }
+
+// openTarballDir is a directory opened from a tarballFS.
+type openTarballDir struct {
+	path    string
+	info    tarballInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (dir *openTarballDir) Stat() (fs.FileInfo, error) { return dir.info, nil }
+func (dir *openTarballDir) Close() error               { return nil }
+
+func (dir *openTarballDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: dir.path, Err: errors.New("is a directory")}
+}
+
+func (dir *openTarballDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries := dir.entries[dir.offset:]
+	if n > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+
+		if n < len(entries) {
+			entries = entries[:n]
+		}
+	}
+
+	dir.offset += len(entries)
+
+	return entries, nil
+}
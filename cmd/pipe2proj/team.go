@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/pipe2proj"
+)
+
+// characters which can't be used in a pipeline name
+var invalidNameCharsRegexp = regexp.MustCompile(`[^\p{Ll}\p{Lt}\p{Lm}\p{Lo}\d\-_.]+`)
+
+// fetchTeamPipelines fetches the config of each of the team's pipelines,
+// leaving out paused or archived ones if asked to. A pipeline which can't be
+// fetched doesn't stop the rest; an error is returned for each one instead.
+func (cmd *Command) fetchTeamPipelines() ([]pipe2proj.Pipeline, []error, error) {
+	api, err := newConcourse(cmd.Context, cmd.Target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	listed, err := api.pipelines(cmd.FetchTeam)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var pipelines []pipe2proj.Pipeline
+	var failures []error
+	named := map[string]string{}
+	for _, pipeline := range listed {
+		name := instancedPipelineName(pipeline)
+
+		log := logrus.WithFields(logrus.Fields{
+			"pipeline": name,
+		})
+
+		if pipeline.Archived && cmd.SkipArchived {
+			log.Info("skipping archived pipeline")
+			continue
+		}
+
+		if pipeline.Paused && cmd.SkipPaused {
+			log.Info("skipping paused pipeline")
+			continue
+		}
+
+		if !identifierRegexp.MatchString(name) {
+			failures = append(failures, fmt.Errorf("pipeline '%s': cannot derive a valid pipeline name", pipeline.Name))
+			continue
+		}
+
+		if other, found := named[name]; found {
+			failures = append(failures, fmt.Errorf("pipeline '%s': would be named '%s', the same as %s", describePipeline(pipeline), name, other))
+			continue
+		}
+
+		named[name] = describePipeline(pipeline)
+
+		config, source, err := api.pipelineConfig(cmd.FetchTeam, pipeline)
+		if err != nil {
+			err = fmt.Errorf("pipeline '%s': %w", name, err)
+			log.WithError(err).Error("failed to fetch pipeline")
+			failures = append(failures, err)
+			continue
+		}
+
+		pipelines = append(pipelines, pipe2proj.Pipeline{
+			Name:   name,
+			Config: config,
+			Source: source,
+		})
+	}
+
+	return pipelines, failures, nil
+}
+
+// instancedPipelineName names an instanced pipeline after its name and each
+// of its instance vars in order, e.g. 'build-branch-main' for the 'build'
+// pipeline with {branch: main}. Characters which aren't valid in a name are
+// replaced with '-'.
+func instancedPipelineName(pipeline atcPipeline) string {
+	if len(pipeline.InstanceVars) == 0 {
+		return pipeline.Name
+	}
+
+	var keys []string
+	for key := range pipeline.InstanceVars {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	segments := []string{pipeline.Name}
+	for _, key := range keys {
+		val, ok := pipeline.InstanceVars[key].(string)
+		if !ok {
+			payload, _ := json.Marshal(pipeline.InstanceVars[key])
+			val = string(payload)
+		}
+
+		segments = append(segments, key, val)
+	}
+
+	name := strings.ToLower(strings.Join(segments, "-"))
+
+	return strings.Trim(invalidNameCharsRegexp.ReplaceAllString(name, "-"), "-")
+}
+
+// describePipeline refers to a pipeline the way fly does, e.g.
+// 'build/branch:main'.
+func describePipeline(pipeline atcPipeline) string {
+	if len(pipeline.InstanceVars) == 0 {
+		return pipeline.Name
+	}
+
+	var vars []string
+	for key, val := range pipeline.InstanceVars {
+		payload, _ := json.Marshal(val)
+		vars = append(vars, key+":"+strings.Trim(string(payload), `"`))
+	}
+
+	sort.Strings(vars)
+
+	return pipeline.Name + "/" + strings.Join(vars, ",")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/vito/pipe2proj"
+)
+
+type cachedTemplates struct {
+	stamp     string
+	templates *pipe2proj.Templates
+}
+
+// templateCache holds the templates parsed for each list of template dirs, so
+// that repeated conversions don't re-parse them unless they change.
+var (
+	templateCache     = map[string]cachedTemplates{}
+	templateCacheLock sync.Mutex
+)
+
+// loadTemplates parses the built-in templates overridden by each of the
+// --config-templates dirs, or returns nil with --no-templates.
+func (cmd *Command) loadTemplates() (*pipe2proj.Templates, error) {
+	if cmd.NoTemplates {
+		return nil, nil
+	}
+
+	var dirs []string
+	for _, dir := range cmd.ConfigTemplates {
+		dirs = append(dirs, dir.Path())
+	}
+
+	key := strings.Join(dirs, "\n")
+	if cmd.AllowMissingKeys {
+		key += "\nallow-missing-keys"
+	}
+
+	stamp, err := templatesStamp(dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	templateCacheLock.Lock()
+	defer templateCacheLock.Unlock()
+
+	cached, found := templateCache[key]
+	if found && cached.stamp == stamp {
+		return cached.templates, nil
+	}
+
+	templates, err := pipe2proj.NewTemplates(cmd.AllowMissingKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dir := range dirs {
+		err := templates.AddDir(os.DirFS(dir))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", dir, err)
+		}
+	}
+
+	templateCache[key] = cachedTemplates{
+		stamp:     stamp,
+		templates: templates,
+	}
+
+	return templates, nil
+}
+
+// templatesStamp describes the template files in each dir, changing whenever
+// one is added, removed, or modified.
+func templatesStamp(dirs []string) (string, error) {
+	stamp := new(bytes.Buffer)
+	for _, dir := range dirs {
+		files, err := pipe2proj.TemplateFiles(os.DirFS(dir))
+		if err != nil {
+			return "", err
+		}
+
+		for _, file := range files {
+			info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(file)))
+			if err != nil {
+				return "", err
+			}
+
+			fmt.Fprintf(stamp, "%s %s %d %d\n", dir, file, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+
+	return stamp.String(), nil
+}
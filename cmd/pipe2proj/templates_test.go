@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const gitTemplate = `---
+type: {{.Type | yaml 0}}
+source:
+  {{.Source | yaml 1}}
+`
+
+// templatesDir creates a dir of templates overriding the one for git
+// resources.
+func templatesDir(t testing.TB) Dir {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "git.tmpl"), []byte(gitTemplate), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Dir(dir)
+}
+
+func TestLoadTemplatesReusesParsedTemplates(t *testing.T) {
+	dir := templatesDir(t)
+	cmd := &Command{ConfigTemplates: []Dir{dir}}
+
+	first, err := cmd.loadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := cmd.loadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second != first {
+		t.Error("expected unchanged templates to be reused")
+	}
+
+	// the stamp includes the size, so this changes it regardless of how
+	// precise mtimes are
+	err = os.WriteFile(filepath.Join(dir.Path(), "git.tmpl"), []byte(gitTemplate+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	third, err := cmd.loadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if third == first {
+		t.Error("expected modified templates to be re-parsed")
+	}
+}
+
+func BenchmarkLoadTemplates(b *testing.B) {
+	cmd := &Command{ConfigTemplates: []Dir{templatesDir(b)}}
+
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := cmd.loadTemplates()
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			templateCacheLock.Lock()
+			templateCache = map[string]cachedTemplates{}
+			templateCacheLock.Unlock()
+
+			_, err := cmd.loadTemplates()
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
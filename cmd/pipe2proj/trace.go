@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vito/pipe2proj"
+)
+
+// printTrace prints the time taken by the conversion as a whole and by each
+// of its phases, and the files slowest to render, slowest first.
+func printTrace(w io.Writer, total time.Duration, trace pipe2proj.Trace) error {
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	phases, files := trace.Slowest()
+
+	fmt.Fprintf(table, "total\t%s\n", roundDuration(total))
+
+	for _, phase := range phases {
+		fmt.Fprintf(table, "%s\t%s\n", phase.Name, roundDuration(phase.Duration))
+	}
+
+	for _, file := range files {
+		fmt.Fprintf(table, "file: %s\t%s\t(render %s, check %s)\n", file.Path, roundDuration(file.Render+file.Check), roundDuration(file.Render), roundDuration(file.Check))
+	}
+
+	return table.Flush()
+}
+
+func roundDuration(d time.Duration) time.Duration {
+	return d.Round(10 * time.Microsecond)
+}
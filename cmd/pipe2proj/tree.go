@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vito/pipe2proj"
+)
+
+// printTree prints the generated files as an indented tree, with each
+// directory's contents listed beneath it.
+func printTree(w io.Writer, root string, files []pipe2proj.GeneratedFile) {
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, filepath.ToSlash(file.Path))
+	}
+
+	sort.Strings(paths)
+
+	fmt.Fprintln(w, root+"/")
+
+	var prevDirs []string
+	for _, path := range paths {
+		segments := strings.Split(path, "/")
+		dirs, name := segments[:len(segments)-1], segments[len(segments)-1]
+
+		common := 0
+		for common < len(dirs) && common < len(prevDirs) && dirs[common] == prevDirs[common] {
+			common++
+		}
+
+		for depth := common; depth < len(dirs); depth++ {
+			fmt.Fprintf(w, "%s%s/\n", strings.Repeat("  ", depth+1), dirs[depth])
+		}
+
+		fmt.Fprintf(w, "%s%s\n", strings.Repeat("  ", len(dirs)+1), name)
+
+		prevDirs = dirs
+	}
+}
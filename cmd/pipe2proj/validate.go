@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/concourse/concourse/atc"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/sirupsen/logrus"
+	"github.com/vito/pipe2proj"
+	"gopkg.in/yaml.v2"
+)
+
+// TargetValidationError is returned when converted pipelines fail to
+// validate for --validate-against, as opposed to failing to convert.
+type TargetValidationError struct {
+	Target string
+	Errors []string
+}
+
+func (err TargetValidationError) Error() string {
+	return fmt.Sprintf("converted pipelines are invalid for target '%s':\n\n%s", err.Target, strings.Join(err.Errors, "\n\n"))
+}
+
+// validateAgainst validates each converted pipeline locally, with the atc
+// validation bundled with pipe2proj, and then prints how it differs from the
+// pipeline of the same name already set on the target. Only the diff comes
+// from the target's Concourse: it can't validate a config without saving it,
+// so newer validations, or checks of e.g. credentials, aren't made.
+func validateAgainst(ctx context.Context, w io.Writer, targetName string, team string, pipelines []pipe2proj.Pipeline) error {
+	api, err := newConcourse(ctx, targetName)
+	if err != nil {
+		return err
+	}
+
+	team = api.team(team)
+
+	var failures []string
+	for _, pipeline := range pipelines {
+		log := logrus.WithFields(logrus.Fields{
+			"target":   targetName,
+			"team":     team,
+			"pipeline": pipeline.Name,
+		})
+
+		log.Info("validating against target")
+
+		var config atc.Config
+		err := yaml.Unmarshal(pipeline.Config, &config)
+		if err != nil {
+			return fmt.Errorf("pipeline '%s': %s", pipeline.Name, err)
+		}
+
+		warnings, errs := config.Validate()
+		for _, warning := range warnings {
+			log.WithField("type", warning.Type).Warn(warning.Message)
+		}
+
+		for _, msg := range errs {
+			failures = append(failures, fmt.Sprintf("pipeline '%s': %s", pipeline.Name, strings.TrimSpace(msg)))
+		}
+
+		live, _, err := api.pipelineConfig(team, atcPipeline{Name: pipeline.Name})
+		if err != nil {
+			var notFound pipelineNotFoundError
+			if errors.As(err, &notFound) {
+				log.Info("pipeline not set on target; nothing to compare")
+				continue
+			}
+
+			return err
+		}
+
+		diff, err := configDiff(live, pipeline.Config)
+		if err != nil {
+			return fmt.Errorf("pipeline '%s': %s", pipeline.Name, err)
+		}
+
+		if diff == "" {
+			log.Info("pipeline matches target")
+			continue
+		}
+
+		fmt.Fprintf(w, "pipeline '%s' differs from %s:\n\n%s\n", pipeline.Name, targetName, diff)
+	}
+
+	if len(failures) > 0 {
+		return TargetValidationError{
+			Target: targetName,
+			Errors: failures,
+		}
+	}
+
+	return nil
+}
+
+// configDiff describes the lines which differ between the two configs, once
+// both are normalized so that only what they configure is compared.
+func configDiff(from []byte, to []byte) (string, error) {
+	fromNormalized, err := normalizeConfig(from)
+	if err != nil {
+		return "", err
+	}
+
+	toNormalized, err := normalizeConfig(to)
+	if err != nil {
+		return "", err
+	}
+
+	if fromNormalized == toNormalized {
+		return "", nil
+	}
+
+	return lineDiff(fromNormalized, toNormalized), nil
+}
+
+// normalizeConfig decodes the config and encodes it again with its mappings'
+// keys sorted.
+func normalizeConfig(payload []byte) (string, error) {
+	var config atc.Config
+	err := yaml.Unmarshal(payload, &config)
+	if err != nil {
+		return "", err
+	}
+
+	decoded, err := yaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	var value interface{}
+	err = yaml.Unmarshal(decoded, &value)
+	if err != nil {
+		return "", err
+	}
+
+	normalized, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(normalized), nil
+}
+
+// lineDiff lists the lines removed and added between the two texts, each
+// change shown beside the lines just before and after it.
+func lineDiff(from string, to string) string {
+	dmp := diffmatchpatch.New()
+
+	fromChars, toChars, lines := dmp.DiffLinesToChars(from, to)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(fromChars, toChars, false), lines)
+
+	var out strings.Builder
+	for i, diff := range diffs {
+		diffLines := strings.Split(strings.TrimSuffix(diff.Text, "\n"), "\n")
+
+		switch diff.Type {
+		case diffmatchpatch.DiffDelete:
+			for _, line := range diffLines {
+				fmt.Fprintln(&out, "- "+line)
+			}
+		case diffmatchpatch.DiffInsert:
+			for _, line := range diffLines {
+				fmt.Fprintln(&out, "+ "+line)
+			}
+		default:
+			// the line after the previous change
+			if i > 0 {
+				fmt.Fprintln(&out, "  "+diffLines[0])
+				diffLines = diffLines[1:]
+			}
+
+			// the line before the next change
+			var next []string
+			if i < len(diffs)-1 && len(diffLines) > 0 {
+				next = diffLines[len(diffLines)-1:]
+				diffLines = diffLines[:len(diffLines)-1]
+			}
+
+			if len(diffLines) > 0 {
+				fmt.Fprintln(&out, "  ...")
+			}
+
+			for _, line := range next {
+				fmt.Fprintln(&out, "  "+line)
+			}
+		}
+	}
+
+	return out.String()
+}
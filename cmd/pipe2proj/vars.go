@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/concourse/concourse/atc"
+	"gopkg.in/yaml.v2"
+)
+
+// loadVars reads each vars file in order, with later files taking precedence.
+func loadVars(files []File) (atc.Source, error) {
+	vars := atc.Source{}
+	for _, file := range files {
+		payload, err := ioutil.ReadFile(file.Path())
+		if err != nil {
+			return nil, err
+		}
+
+		var fileVars atc.Source
+		err = yaml.Unmarshal(payload, &fileVars)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %s", file.Path(), err)
+		}
+
+		for k, v := range fileVars {
+			vars[k] = v
+		}
+	}
+
+	return vars, nil
+}
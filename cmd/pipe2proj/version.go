@@ -0,0 +1,40 @@
+package main
+
+import (
+	"runtime/debug"
+	"strings"
+)
+
+// set when building releases, e.g. with
+// -ldflags "-X main.version=v1.2.3 -X main.commit=abc123 -X main.date=2021-01-01"
+var (
+	version = "devel"
+	commit  = ""
+	date    = ""
+)
+
+// versionString describes the build, falling back on the module version
+// when installed with e.g. 'go install ...@v1.2.3'.
+func versionString() string {
+	v := version
+	if v == "devel" {
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			v = info.Main.Version
+		}
+	}
+
+	var details []string
+	if commit != "" {
+		details = append(details, "commit "+commit)
+	}
+
+	if date != "" {
+		details = append(details, "built "+date)
+	}
+
+	if len(details) == 0 {
+		return v
+	}
+
+	return v + " (" + strings.Join(details, ", ") + ")"
+}
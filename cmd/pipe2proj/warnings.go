@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/pipe2proj"
+)
+
+// warningCounter is a logrus hook counting the warnings logged, so that
+// --fail-on-warning catches every one, wherever it's logged from.
+type warningCounter struct {
+	count int64
+}
+
+func (counter *warningCounter) Levels() []logrus.Level {
+	return []logrus.Level{logrus.WarnLevel}
+}
+
+func (counter *warningCounter) Fire(*logrus.Entry) error {
+	atomic.AddInt64(&counter.count, 1)
+	return nil
+}
+
+// reset starts counting again, e.g. for each conversion with --watch.
+func (counter *warningCounter) reset() {
+	atomic.StoreInt64(&counter.count, 0)
+}
+
+// check fails if any warnings were logged since the last reset.
+func (counter *warningCounter) check() error {
+	count := atomic.LoadInt64(&counter.count)
+	if count == 0 {
+		return nil
+	}
+
+	return pipe2proj.ValidationError{
+		Message: fmt.Sprintf("%d warnings were logged, failing due to --fail-on-warning", count),
+	}
+}
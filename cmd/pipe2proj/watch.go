@@ -0,0 +1,172 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// watchDebounce is how long to wait for changes to settle before converting,
+// so that e.g. an editor saving several files only triggers one conversion.
+const watchDebounce = 250 * time.Millisecond
+
+// watch converts the pipeline and then converts it again whenever the
+// pipeline config or anything in a task artifact changes.
+func (cmd *Command) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	defer watcher.Close()
+
+	// watch the configs' directories rather than the files themselves, so
+	// that editors which save by replacing the file don't end the watch
+	if cmd.PipelinesDir != "" {
+		err = watcher.Add(cmd.PipelinesDir.Path())
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, file := range cmd.PipelineConfigs {
+		err := watcher.Add(filepath.Dir(file.Path()))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, file := range cmd.VarsFiles {
+		err := watcher.Add(filepath.Dir(file.Path()))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, artifact := range cmd.TaskResources {
+		if artifact.IsTarball() {
+			err := watcher.Add(filepath.Dir(artifact.Path()))
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		err := watchTree(watcher, artifact.Path())
+		if err != nil {
+			return err
+		}
+	}
+
+	cmd.convertAndLog()
+
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-cmd.Context.Done():
+			logrus.Info("interrupted; no longer watching")
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if !cmd.isWatched(event.Name) {
+				continue
+			}
+
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					err := watchTree(watcher, event.Name)
+					if err != nil {
+						logrus.WithError(err).Warn("failed to watch directory")
+					}
+				}
+			}
+
+			settled = time.After(watchDebounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			logrus.WithError(err).Warn("watch error")
+
+		case <-settled:
+			settled = nil
+			cmd.convertAndLog()
+		}
+	}
+}
+
+func (cmd *Command) convertAndLog() {
+	err := cmd.convert()
+	if err != nil {
+		logrus.WithError(err).Error("conversion failed")
+		return
+	}
+
+	logrus.Info("converted")
+}
+
+// isWatched determines whether a change to the path should trigger a
+// conversion. Changes within the project itself are ignored, in case it
+// lives within a task artifact.
+func (cmd *Command) isWatched(path string) bool {
+	if within(path, cmd.ProjectPath.Path()) {
+		return false
+	}
+
+	for _, file := range cmd.PipelineConfigs {
+		if path == file.Path() {
+			return true
+		}
+	}
+
+	if cmd.PipelinesDir != "" && filepath.Dir(path) == cmd.PipelinesDir.Path() && isPipelineFile(path) {
+		return true
+	}
+
+	for _, file := range cmd.VarsFiles {
+		if path == file.Path() {
+			return true
+		}
+	}
+
+	for _, artifact := range cmd.TaskResources {
+		if within(path, artifact.Path()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func watchTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		if info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		return watcher.Add(path)
+	})
+}
+
+func within(path string, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
@@ -0,0 +1,1584 @@
+package pipe2proj
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/concourse/concourse/atc"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// Options configures a conversion. Fields with flags are shared with the
+// pipe2proj command; the rest are given by whatever calls Convert.
+type Options struct {
+	ProjectName  string `long:"project-name"  short:"n" env:"P2P_PROJECT_NAME" description:"Name to give to the project, e.g. 'ci'."`
+	PipelineName string `long:"pipeline-name" short:"p" env:"P2P_PIPELINE_NAME" description:"Name to give to the pipeline within the project. Defaults to the name of --from-pipeline or --pipeline-config's file name, e.g. 'main' for 'main.yml'."`
+
+	// Config is the pipeline config to convert, and ConfigSource describes
+	// where it came from, e.g. its path.
+	Config       []byte `no-flag:"true"`
+	ConfigSource string `no-flag:"true"`
+
+	// Pipelines, if given, are converted into the project in place of
+	// PipelineName and Config, sharing its resources, resource types, and
+	// tasks.
+	Pipelines []Pipeline `no-flag:"true"`
+
+	// MergePipelines merges the Pipelines into a single pipeline named
+	// PipelineName rather than converting each of them.
+	MergePipelines bool `no-flag:"true"`
+
+	MultiDoc string `long:"multi-doc" choice:"refuse" choice:"concat" default:"refuse" env:"P2P_MULTI_DOC" description:"What to do with a pipeline config file of several pipelines as separate YAML documents, e.g. concatenated exports: 'refuse' to convert it, or 'concat' them into one, appending their groups, resources, resource types, and jobs. Later documents of vars are allowed either way."`
+
+	KeepGoing bool `long:"keep-going" env:"P2P_KEEP_GOING" description:"When converting several pipelines, keep converting the rest when one fails, reporting every failure at the end."`
+	MaxErrors int  `long:"max-errors" value-name:"N" env:"P2P_MAX_ERRORS" description:"With --keep-going, only describe the first N failures at the end, noting how many more there were."`
+
+	// TaskArtifacts maps artifact names to their content, from which tasks
+	// and their scripts are converted.
+	TaskArtifacts map[string]fs.FS `no-flag:"true"`
+
+	// Vars are interpolated into task file paths.
+	Vars atc.Source `no-flag:"true"`
+
+	// Context stops the conversion once it's cancelled: no further phases
+	// are started, and any post-render or step filter command running is
+	// killed. Defaults to context.Background().
+	Context context.Context `no-flag:"true"`
+
+	RewriteSources []SourceRewrite `long:"rewrite-source" value-name:"TYPE.KEY=REGEX=>REPLACEMENT" env:"P2P_REWRITE_SOURCE" env-delim:"\n" description:"Rewrite a string value in the source of every resource and resource type of the given type. Can be given multiple times."`
+
+	SkipCoreResourceTypes bool     `long:"skip-core-resource-types" env:"P2P_SKIP_CORE_RESOURCE_TYPES" description:"Leave declarations of core resource types out of the project."`
+	CoreResourceTypes     []string `long:"core-resource-type" value-name:"NAME" env:"P2P_CORE_RESOURCE_TYPE" env-delim:"," description:"Resource type to consider core when skipping. Defaults to the types bundled with Concourse."`
+
+	KeepUnusedResourceTypes bool `long:"keep-unused-resource-types" env:"P2P_KEEP_UNUSED_RESOURCE_TYPES" description:"Convert resource types even if no resource uses them."`
+
+	WarnUnusedResources bool `long:"warn-unused-resources" env:"P2P_WARN_UNUSED_RESOURCES" description:"Warn about resources which no get or put step refers to."`
+
+	ExternalizeWebhookTokens bool `long:"externalize-webhook-tokens" env:"P2P_EXTERNALIZE_WEBHOOK_TOKENS" description:"Replace literal webhook tokens with ((<resource>-webhook-token)) vars, writing their values to vars/webhooks.yml to be moved into a credential manager."`
+
+	AutoIcons bool `long:"auto-icons" env:"P2P_AUTO_ICONS" description:"Give resources without an icon one based on their type, e.g. 'git' for git resources."`
+
+	// Icons, if given, are used by AutoIcons for resources of each type,
+	// taking precedence over the defaults.
+	Icons map[string]string `no-flag:"true"`
+
+	KeepResourceNames bool `long:"keep-resource-names" env:"P2P_KEEP_RESOURCE_NAMES" description:"Include each resource and resource type's name in its generated file."`
+
+	SortJobs      bool `long:"sort-jobs" env:"P2P_SORT_JOBS" description:"Sort jobs by name, rather than keeping them in the order of the pipeline config. Implied by --sort-output=name."`
+	SortResources bool `long:"sort-resources" env:"P2P_SORT_RESOURCES" description:"Sort resources and resource types by name, rather than keeping them in the order of the pipeline config. Implied by --sort-output."`
+
+	SortOutput string `long:"sort-output" optional:"true" optional-value:"name" choice:"name" choice:"deps" env:"P2P_SORT_OUTPUT" description:"Sort resources, resource types, groups, and jobs by name. With 'deps', jobs are instead sorted by their passed constraints."`
+
+	SetSources []SourceOverride `long:"set-source" value-name:"RESOURCE.KEY=VALUE" env:"P2P_SET_SOURCE" env-delim:"\n" description:"Set a string value in a resource's source, adding the key if it isn't there. KEY may be a dot-separated path into nested config. Can be given multiple times."`
+
+	RenameResources []ResourceRename `long:"rename-resource" value-name:"OLD=NEW" env:"P2P_RENAME_RESOURCE" env-delim:"," description:"Rename a resource, rewriting all references to it. Can be given multiple times."`
+
+	ExcludeJobs []string `long:"exclude-job" value-name:"NAME" env:"P2P_EXCLUDE_JOB" env-delim:"," description:"Leave a job out of the converted pipeline, removing it from any groups. Can be given multiple times."`
+
+	CheckTaskInputs bool `long:"check-task-inputs" env:"P2P_CHECK_TASK_INPUTS" description:"Warn about task inputs which aren't fetched or produced by any step before the task, accounting for input_mapping."`
+
+	SplitByGroup bool   `long:"split-by-group" env:"P2P_SPLIT_BY_GROUP" description:"Convert each of the pipeline's groups into its own pipeline, named after the group, sharing resources between them. Each job must be in exactly one group."`
+	DefaultGroup string `long:"default-group" value-name:"NAME" env:"P2P_DEFAULT_GROUP" description:"Group to put jobs which aren't in any group in when splitting by group."`
+
+	DefaultTaskPlatform string `long:"default-task-platform" value-name:"PLATFORM" env:"P2P_DEFAULT_TASK_PLATFORM" description:"Platform to set on converted tasks which don't specify one."`
+
+	DefaultTaskTimeout string   `long:"default-task-timeout" value-name:"DURATION" env:"P2P_DEFAULT_TASK_TIMEOUT" description:"Timeout to set on tasks which don't specify one."`
+	DefaultStepTimeout string   `long:"default-step-timeout" value-name:"DURATION" env:"P2P_DEFAULT_STEP_TIMEOUT" description:"Timeout to set on get, put, and task steps which don't specify one. Can't be given with --default-task-timeout."`
+	TaskTags           []string `long:"task-tag" value-name:"TAG" env:"P2P_TASK_TAG" env-delim:"," description:"Tag to add to every task. Can be given multiple times."`
+
+	StepFilter string `long:"step-filter" value-name:"CMD" env:"P2P_STEP_FILTER" description:"Shell command to pipe each step through as YAML once tasks are converted, replacing the step with its output. Empty output leaves the step as-is. $P2P_JOB and $P2P_STEP_PATH say which step it is."`
+
+	// StepTransforms are run over every step in every job's plan, after the
+	// built-in transforms enabled by flags.
+	StepTransforms []StepTransform `no-flag:"true"`
+
+	Defaults string `long:"defaults" choice:"expand" choice:"strip" choice:"preserve" default:"preserve" env:"P2P_DEFAULTS" description:"Spell out fields of resources, resource types, and tasks which Concourse would default, e.g. 'check_every: 1m', with 'expand', or remove those set to their defaults with 'strip'."`
+
+	LineEndings string `long:"line-endings" choice:"lf" choice:"crlf" choice:"preserve" default:"preserve" env:"P2P_LINE_ENDINGS" description:"Line endings for converted scripts and generated YAML files: 'lf', 'crlf', or those of the originals."`
+
+	NormalizeScripts bool `long:"normalize-scripts" env:"P2P_NORMALIZE_SCRIPTS" description:"Trim trailing whitespace from each line of converted scripts and end them with exactly one newline."`
+
+	DedupeScripts bool `long:"dedupe-scripts" env:"P2P_DEDUPE_SCRIPTS" description:"Write scripts with identical content only once, under the lexicographically smallest name. Scripts of the same name with different content are an error."`
+
+	EmitFlyScript bool `long:"emit-fly-script" env:"P2P_EMIT_FLY_SCRIPT" description:"Write a scripts/set-pipelines.sh script to the project which sets each converted pipeline with fly."`
+
+	Flat bool `long:"flat" env:"P2P_FLAT" description:"Pretty-print the whole pipeline into a single file rather than extracting its resources, resource types, and tasks."`
+
+	ExtractImages bool `long:"extract-images" env:"P2P_EXTRACT_IMAGES" description:"Write each distinct image_resource used by converted tasks to the project's images directory, starting each task file with a comment naming its image and reporting which tasks share each one."`
+
+	ExternalizeRegistryCredentials bool `long:"externalize-registry-credentials" env:"P2P_EXTERNALIZE_REGISTRY_CREDENTIALS" description:"With --extract-images, replace literal usernames, passwords, and tokens in the images' sources with ((<image>-<key>)) vars, in the images and the tasks using them, writing their values to vars/registry.yml to be moved into a credential manager."`
+
+	NamespaceTasks bool `long:"namespace-tasks" env:"P2P_NAMESPACE_TASKS" description:"Place converted tasks and scripts in a subdirectory named after the pipeline."`
+
+	// Templates pretty-print the generated files. The built-in templates are
+	// used if none are given.
+	Templates *Templates `no-flag:"true"`
+
+	NoTemplates bool `long:"no-templates" env:"P2P_NO_TEMPLATES" description:"Write values as marshalled, without pretty-printing them through the built-in templates."`
+
+	TemplateContext bool         `long:"template-context" env:"P2P_TEMPLATE_CONTEXT" description:"Give templates from --config-templates a context with the project, pipeline, kind, and name alongside the value, which becomes .Value."`
+	TemplateData    TemplateData `long:"template-data" value-name:"JSON" env:"P2P_TEMPLATE_DATA" description:"JSON object to give templates in their --template-context as .Extra, e.g. '{\"team\": \"ci\"}'."`
+
+	YAMLIndent          int  `long:"yaml-indent" value-name:"N" env:"P2P_YAML_INDENT" description:"Number of spaces to indent generated YAML by. Defaults to 2."`
+	YAMLIndentSequences bool `long:"yaml-indent-sequences" env:"P2P_YAML_INDENT_SEQUENCES" description:"Indent sequence entries beneath their parent key rather than aligning the dashes with it."`
+
+	SortKeys bool `long:"sort-keys" env:"P2P_SORT_KEYS" description:"Sort the keys of every mapping in the generated files, including those the templates order by hand."`
+
+	YAMLDocumentMarker bool `long:"yaml-document-marker" env:"P2P_YAML_DOCUMENT_MARKER" description:"Start each rendered YAML file with a '---' document marker, e.g. for tools which concatenate them."`
+
+	PostRenderCmd      string            `long:"post-render-cmd" value-name:"CMD" env:"P2P_POST_RENDER_CMD" description:"Shell command to pipe each rendered YAML file through before it is written, e.g. 'yamlfmt -'."`
+	PostRenderKindCmds map[string]string `long:"post-render-kind-cmd" value-name:"KIND:CMD" env:"P2P_POST_RENDER_KIND_CMD" env-delim:"\n" description:"Shell command to pipe rendered files of the given kind (resource, resource-type, task, pipeline, project) through, in place of --post-render-cmd."`
+
+	Lint string `long:"lint" optional:"true" optional-value:"warn" choice:"warn" choice:"strict" env:"P2P_LINT" description:"Warn about deprecated and discouraged constructs. With 'strict', any warning is fatal."`
+
+	// Writer, if given, is called with each file as it's generated, e.g. to
+	// write it into a project on disk. The conversion fails if it returns an
+	// error.
+	Writer Writer `no-flag:"true"`
+}
+
+// Pipeline is one of several pipelines converted into the same project.
+type Pipeline struct {
+	Name string
+
+	// the pipeline config, and where it came from, e.g. its path
+	Config []byte
+	Source string
+}
+
+// Writer writes generated files somewhere, e.g. into a project on disk.
+type Writer interface {
+	WriteFile(GeneratedFile) error
+}
+
+// Result is the outcome of a conversion.
+type Result struct {
+	// Files generated for the project, in the order they were generated.
+	Files []GeneratedFile
+
+	// Warnings logged during the conversion.
+	Warnings []Warning
+
+	// Graph of the jobs and resources of each converted pipeline.
+	Graph Graph
+
+	// Overview of the converted pipelines, resources, and tasks.
+	Overview Overview
+
+	// Stats counting what was converted.
+	Stats Stats
+
+	// Trace of the time taken by each phase of the conversion.
+	Trace Trace
+
+	// Pipelines converted, each reassembled into a single config as it could
+	// be set with fly, with its resources and converted tasks inline.
+	Pipelines []Pipeline
+}
+
+// Warning is a problem with the pipeline which doesn't prevent converting it.
+type Warning struct {
+	Fields  logrus.Fields
+	Message string
+}
+
+// GeneratedFile is a file generated for the project.
+type GeneratedFile struct {
+	// path relative to the project
+	Path string
+
+	// what the file is (resource, task, etc.), the name of what it defines,
+	// and what it was converted from
+	Kind   string
+	Name   string
+	Source string
+
+	// the pipelines the file was generated for, in the order they were
+	// converted; empty for files covering the whole project, e.g. project.yml
+	Pipelines []string
+
+	Payload []byte
+	Mode    os.FileMode
+}
+
+type convertedTask struct {
+	Name   string
+	Path   string
+	Source string
+	Config atc.TaskConfig
+
+	// the job it was converted for
+	Job string
+
+	// name of the script the task runs, if it was converted
+	Script string
+}
+
+type convertedScript struct {
+	Name    string
+	Source  string
+	Payload []byte
+}
+
+// TemplateContext is given to templates from --config-templates in place of
+// the value being rendered when --template-context is set.
+type TemplateContext struct {
+	Project  string
+	Pipeline string
+	Kind     string
+	Name     string
+	Value    interface{}
+
+	// data given by --template-data
+	Extra map[string]interface{}
+}
+
+type ProjectConfig struct {
+	Name string
+	Plan []map[string]string // XXX: hacky - set_pipeline doesn't exist yet
+}
+
+// PipelineConfig is just atc.Config with omitempty everywhere.
+type PipelineConfig struct {
+	Groups        atc.GroupConfigs    `yaml:"groups,omitempty"`
+	Resources     atc.ResourceConfigs `yaml:"resources,omitempty"`
+	ResourceTypes atc.ResourceTypes   `yaml:"resource_types,omitempty"`
+	Jobs          atc.JobConfigs      `yaml:"jobs,omitempty"`
+}
+
+type AnonymousResourceConfig struct {
+	// only set with --keep-resource-names
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// the name of the resource, whether or not it's kept, for use in templates
+	ResourceName string `yaml:"-" json:"-"`
+
+	Public       bool        `yaml:"public,omitempty" json:"public,omitempty"`
+	WebhookToken string      `yaml:"webhook_token,omitempty" json:"webhook_token,omitempty"`
+	Type         string      `yaml:"type" json:"type"`
+	Source       atc.Source  `yaml:"source" json:"source"`
+	CheckEvery   string      `yaml:"check_every,omitempty" json:"check_every,omitempty"`
+	CheckTimeout string      `yaml:"check_timeout,omitempty" json:"check_timeout,omitempty"`
+	Tags         atc.Tags    `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Version      atc.Version `yaml:"version,omitempty" json:"version,omitempty"`
+	Icon         string      `yaml:"icon,omitempty" json:"icon,omitempty"`
+
+	// only set for resource types
+	Privileged           bool       `yaml:"privileged,omitempty" json:"privileged,omitempty"`
+	Params               atc.Params `yaml:"params,omitempty" json:"params,omitempty"`
+	UniqueVersionHistory bool       `yaml:"unique_version_history,omitempty" json:"unique_version_history,omitempty"`
+}
+
+// converter holds the state of a single conversion.
+type converter struct {
+	Options
+
+	// the pipeline currently being converted, to which written files are
+	// attributed
+	pipeline string
+
+	result *Result
+}
+
+// Convert converts the pipeline config into a project, returning the
+// generated files. Nothing is written anywhere unless opts.Writer is given.
+//
+// When some of several pipelines fail to convert with KeepGoing set, the
+// files generated for the rest are returned along with PipelineErrors.
+func Convert(opts Options) (*Result, error) {
+	c, err := newConverter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.convert()
+	if err != nil {
+		if _, ok := err.(PipelineErrors); ok {
+			return c.result, err
+		}
+
+		return nil, err
+	}
+
+	return c.result, nil
+}
+
+func newConverter(opts Options) (*converter, error) {
+	// each would set the timeout of tasks, depending on which ran first
+	if opts.DefaultTaskTimeout != "" && opts.DefaultStepTimeout != "" {
+		return nil, invalidf("--default-task-timeout and --default-step-timeout cannot be given together; --default-step-timeout covers tasks too")
+	}
+
+	if opts.Context == nil {
+		opts.Context = context.Background()
+	}
+
+	if opts.NoTemplates {
+		// render the raw marshalled values
+		opts.Templates = nil
+	} else if opts.Templates == nil {
+		builtin, err := NewTemplates(false)
+		if err != nil {
+			return nil, fmt.Errorf("loading templates: %s", err)
+		}
+
+		opts.Templates = builtin
+	}
+
+	return &converter{
+		Options: opts,
+		result:  &Result{},
+	}, nil
+}
+
+func (c *converter) convert() error {
+	pipelines := c.Pipelines
+	if len(pipelines) == 0 {
+		pipelines = []Pipeline{{
+			Name:   c.PipelineName,
+			Config: c.Config,
+			Source: c.ConfigSource,
+		}}
+	}
+
+	for _, pipeline := range pipelines {
+		c.result.Stats.LinesBefore += countLines(pipeline.Config)
+	}
+
+	if c.MergePipelines && len(pipelines) > 1 {
+		merging := c.result.Trace.phase("merge pipelines")
+		merged, err := c.mergePipelines(pipelines)
+		if err != nil {
+			return err
+		}
+
+		merging()
+
+		pipelines = []Pipeline{merged}
+	}
+
+	if c.SplitByGroup {
+		if len(pipelines) > 1 {
+			return fmt.Errorf("only a single pipeline can be split by group")
+		}
+
+		splitting := c.result.Trace.phase("split pipeline")
+
+		var err error
+		pipelines, err = c.splitPipeline(pipelines[0])
+		if err != nil {
+			return err
+		}
+
+		splitting()
+	}
+
+	var converted []string
+	var failures []error
+	var externalized externalizedVars
+
+	// vars files written for each pipeline, to set it with
+	varsFiles := map[string][]string{}
+	for _, pipeline := range pipelines {
+		if err := c.Context.Err(); err != nil {
+			return err
+		}
+
+		c.PipelineName = pipeline.Name
+		c.Config = pipeline.Config
+		c.ConfigSource = pipeline.Source
+		c.pipeline = pipeline.Name
+
+		vars, err := c.convertPipeline()
+
+		c.pipeline = ""
+
+		if err == nil {
+			externalized, err = externalized.merge(*vars)
+		}
+
+		if err != nil {
+			if len(pipelines) == 1 {
+				return err
+			}
+
+			err = fmt.Errorf("pipeline '%s': %w", pipeline.Name, err)
+			if !c.KeepGoing || c.Context.Err() != nil {
+				return err
+			}
+
+			logrus.WithError(err).Error("failed to convert pipeline")
+
+			failures = append(failures, err)
+
+			continue
+		}
+
+		converted = append(converted, pipeline.Name)
+
+		if len(vars.WebhookTokens) > 0 {
+			varsFiles[pipeline.Name] = append(varsFiles[pipeline.Name], webhookTokensPath)
+		}
+
+		if len(vars.RegistryCredentials) > 0 {
+			varsFiles[pipeline.Name] = append(varsFiles[pipeline.Name], registryCredentialsPath)
+		}
+	}
+
+	if len(pipelines) > 1 {
+		logrus.WithFields(logrus.Fields{
+			"converted": len(converted),
+			"failed":    len(failures),
+		}).Info("converted pipelines")
+	}
+
+	if err := c.Context.Err(); err != nil {
+		return err
+	}
+
+	if len(converted) > 0 {
+		projectDone := c.result.Trace.phase("project files")
+		err := c.writeProjectFiles(converted, externalized, varsFiles)
+		if err != nil {
+			return err
+		}
+
+		projectDone()
+	}
+
+	c.result.Stats.addFiles(c.result.Files)
+
+	if len(failures) > 0 {
+		return PipelineErrors{
+			Errors: failures,
+			Total:  len(pipelines),
+			Max:    c.MaxErrors,
+		}
+	}
+
+	return nil
+}
+
+// convertPipeline converts the current pipeline, returning any webhook tokens
+// it externalized.
+func (c *converter) convertPipeline() (*externalizedVars, error) {
+	parsed := c.phase("parse")
+	config, vars, err := parsePipeline(stripBOM(c.Config), c.MultiDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed()
+
+	// vars given to the converter take precedence over those in the file
+	for k, v := range c.Vars {
+		vars[k] = v
+	}
+
+	// a pipeline split by group has already been prepared as a whole, since
+	// e.g. a resource being renamed needn't be in every group
+	if !c.SplitByGroup {
+		err = c.prepareConfig(&config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var externalized externalizedVars
+	if c.ExternalizeWebhookTokens {
+		externalized.WebhookTokens = externalizeWebhookTokens(&config)
+	}
+
+	if c.SortOutput != "" {
+		logrus.WithFields(logrus.Fields{
+			"order": c.SortOutput,
+		}).Info("sorting output")
+
+		err = sortConfig(&config, c.SortOutput)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.SortJobs {
+		sortJobs(&config)
+	}
+
+	if c.SortResources {
+		sortResources(&config)
+	}
+
+	originalNames := map[string]string{}
+	for _, rename := range c.RenameResources {
+		originalNames[rename.New] = rename.Old
+	}
+
+	pipelinesPath := "pipelines"
+	tasksPath := "tasks"
+	scriptsPath := filepath.Join("tasks", "scripts")
+	resourcesPath := "resources"
+	resourceTypesPath := "resource-types"
+	imagesPath := "images"
+
+	var taskNamespace string
+	if c.NamespaceTasks {
+		taskNamespace = c.PipelineName
+	}
+
+	var lints []Warning
+	if c.Lint != "" {
+		lints = append(lints, lintPipeline(config)...)
+	}
+
+	if c.AutoIcons {
+		assignIcons(&config, c.Icons)
+	}
+
+	if c.WarnUnusedResources {
+		unused, err := unusedResources(config)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range unused {
+			c.warn(logrus.Fields{
+				"resource": name,
+			}, "resource is never used")
+		}
+	}
+
+	var tasks []convertedTask
+	var scripts []convertedScript
+
+	// types of the images used by tasks, which may be custom resource types
+	var imageTypes []string
+
+	// iterate over artifacts in a stable order so that conversion doesn't
+	// depend on map ordering
+	var artifactNames []string
+	for name := range c.TaskArtifacts {
+		artifactNames = append(artifactNames, name)
+	}
+
+	sort.Strings(artifactNames)
+
+	transforms := c.stepTransforms()
+
+	newJobs := []atc.JobConfig{}
+	for _, j := range config.Jobs {
+		if err := c.Context.Err(); err != nil {
+			return nil, err
+		}
+
+		jobDone := c.phase(fmt.Sprintf("job '%s'", j.Name))
+
+		// configs of the tasks converted from files, for checking inputs
+		taskConfigs := map[StepPath]atc.TaskConfig{}
+
+		newJob, err := WalkJob(j, func(stepPath StepPath, p atc.PlanConfig) (atc.PlanConfig, error) {
+			for _, transform := range transforms {
+				p = transform(p)
+			}
+
+			if c.Lint != "" {
+				fields := logrus.Fields{"job": j.Name}
+				if p.Name() != "" {
+					fields["step"] = p.Name()
+				}
+
+				lints = append(lints, lintStep(fields, p)...)
+			}
+
+			if p.Task == "" || c.Flat {
+				return p, nil
+			}
+
+			if p.TaskConfig != nil && p.TaskConfig.ImageResource != nil {
+				imageTypes = append(imageTypes, p.TaskConfig.ImageResource.Type)
+			}
+
+			if p.TaskConfigPath == "" {
+				return p, nil
+			}
+
+			log := logrus.WithFields(logrus.Fields{
+				"file": p.TaskConfigPath,
+			})
+
+			taskConfigPath, unresolved := interpolateVars(p.TaskConfigPath, vars)
+			if len(unresolved) > 0 {
+				c.warn(logrus.Fields{
+					"file": p.TaskConfigPath,
+					"vars": unresolved,
+				}, "not converting task; its file path has unresolved vars")
+
+				return p, nil
+			}
+
+			taskName := strings.TrimSuffix(filepath.Base(taskConfigPath), ".yml")
+			taskPath := filepath.Join(tasksPath, taskNamespace, taskName+".yml")
+
+			for _, artifactName := range artifactNames {
+				artifact := c.TaskArtifacts[artifactName]
+				prefix := artifactName + "/"
+
+				if !strings.HasPrefix(taskConfigPath, prefix) {
+					continue
+				}
+
+				log.Info("converting task")
+
+				taskPayload, err := fs.ReadFile(artifact, path.Clean(strings.TrimPrefix(taskConfigPath, prefix)))
+				if err != nil {
+					return p, fmt.Errorf("%s: loading task: %s", stepPath, err)
+				}
+
+				taskPayload = stripBOM(taskPayload)
+
+				var taskConfig atc.TaskConfig
+				err = yaml.Unmarshal(taskPayload, &taskConfig)
+				if err != nil {
+					return p, fmt.Errorf("%s: parsing task config %s: %s", stepPath, taskConfigPath, explainVarTypeError(taskPayload, err))
+				}
+
+				if taskConfig.ImageResource != nil {
+					imageTypes = append(imageTypes, taskConfig.ImageResource.Type)
+				}
+
+				taskConfigs[stepPath] = taskConfig
+
+				if taskConfig.Platform == "" {
+					taskConfig.Platform = c.DefaultTaskPlatform
+				}
+
+				if c.Lint != "" {
+					lints = append(lints, lintTask(logrus.Fields{"job": j.Name, "task": taskName}, taskConfig)...)
+				}
+
+				task := convertedTask{
+					Name:   taskName,
+					Path:   taskPath,
+					Source: p.TaskConfigPath,
+					Config: taskConfig,
+					Job:    j.Name,
+				}
+
+				if strings.HasPrefix(taskConfig.Run.Path, prefix) {
+					log.WithFields(logrus.Fields{
+						"script": taskConfig.Run.Path,
+					}).Info("converting script")
+
+					scriptPayload, err := fs.ReadFile(artifact, path.Clean(strings.TrimPrefix(taskConfig.Run.Path, prefix)))
+					if err != nil {
+						return p, fmt.Errorf("%s: loading script: %s", stepPath, err)
+					}
+
+					scriptPayload = stripBOM(scriptPayload)
+
+					if c.NormalizeScripts {
+						scriptPayload = normalizeScript(scriptPayload)
+					}
+
+					scriptPayload = convertLineEndings(scriptPayload, c.LineEndings)
+
+					task.Script = filepath.Base(taskConfig.Run.Path)
+					task.Config.Inputs = append([]atc.TaskInputConfig{{Name: c.ProjectName}}, taskConfig.Inputs...)
+
+					scripts = append(scripts, convertedScript{
+						Name:    task.Script,
+						Source:  taskConfig.Run.Path,
+						Payload: scriptPayload,
+					})
+				}
+
+				tasks = append(tasks, task)
+
+				p.TaskConfigPath = ""
+				p.Task = path.Join(taskNamespace, taskName)
+			}
+
+			return p, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("job '%s': %w", j.Name, err)
+		}
+
+		if c.CheckTaskInputs {
+			for _, warning := range missingTaskInputs(j, taskConfigs) {
+				c.warn(warning.Fields, warning.Message)
+			}
+		}
+
+		if c.StepFilter != "" {
+			newJob, err = WalkJob(newJob, func(stepPath StepPath, p atc.PlanConfig) (atc.PlanConfig, error) {
+				return filterStep(c.Context, c.StepFilter, j.Name, stepPath, p)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("job '%s': %w", j.Name, err)
+			}
+		}
+
+		newJobs = append(newJobs, newJob)
+
+		jobDone()
+	}
+
+	// every lint has been collected by now, before anything is written, so
+	// that failing strict linting leaves nothing behind
+	for _, lint := range lints {
+		c.warn(lint.Fields, lint.Message)
+	}
+
+	if c.Lint == "strict" && len(lints) > 0 {
+		return nil, invalidf("lint failed with %d warnings", len(lints))
+	}
+
+	if err := c.Context.Err(); err != nil {
+		return nil, err
+	}
+
+	resourcesDone := c.phase("resources")
+	for _, res := range config.Resources {
+		if c.Flat {
+			break
+		}
+
+		resourcePath := filepath.Join(resourcesPath, res.Name+".yml")
+
+		logrus.WithFields(logrus.Fields{
+			"name": res.Name,
+		}).Info("converting resource")
+
+		source := res.Name
+		if original, found := originalNames[res.Name]; found {
+			source = original
+		}
+
+		anon, err := anonymize(res, c.KeepResourceNames)
+		if err != nil {
+			return nil, invalidf("resource '%s' of type '%s': %s", res.Name, res.Type, err)
+		}
+
+		applyResourceDefaults(&anon, c.Defaults, false)
+
+		err = c.render(GeneratedFile{
+			Path:   resourcePath,
+			Kind:   "resource",
+			Name:   res.Name,
+			Source: source,
+		}, c.Templates.resourceTemplate(res.Type), anon)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resourcesDone()
+
+	var skippedTypes map[string]bool
+	if c.SkipCoreResourceTypes {
+		names := c.CoreResourceTypes
+		if len(names) == 0 {
+			names = coreResourceTypes
+		}
+
+		var warnings []Warning
+		skippedTypes, warnings = skippedResourceTypes(config, names)
+
+		for _, warning := range warnings {
+			c.warn(warning.Fields, warning.Message)
+		}
+	}
+
+	usedTypes := usedResourceTypes(config, imageTypes)
+
+	if err := c.Context.Err(); err != nil {
+		return nil, err
+	}
+
+	resourceTypesDone := c.phase("resource types")
+	for _, res := range config.ResourceTypes {
+		if c.Flat {
+			break
+		}
+
+		if skippedTypes[res.Name] {
+			logrus.WithFields(logrus.Fields{
+				"name": res.Name,
+			}).Info("skipping core resource type")
+
+			continue
+		}
+
+		if !usedTypes[res.Name] && !c.KeepUnusedResourceTypes {
+			logrus.WithFields(logrus.Fields{
+				"name": res.Name,
+			}).Info("omitting unused resource type")
+
+			continue
+		}
+
+		resourceTypePath := filepath.Join(resourceTypesPath, res.Name+".yml")
+
+		logrus.WithFields(logrus.Fields{
+			"name": res.Name,
+		}).Info("converting resource type")
+
+		anon, err := anonymize(res, c.KeepResourceNames)
+		if err != nil {
+			return nil, invalidf("resource type '%s' of type '%s': %s", res.Name, res.Type, err)
+		}
+
+		applyResourceDefaults(&anon, c.Defaults, true)
+
+		err = c.render(GeneratedFile{
+			Path:   resourceTypePath,
+			Kind:   "resource-type",
+			Name:   res.Name,
+			Source: res.Name,
+		}, c.Templates.resourceTemplate(res.Type), anon)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resourceTypesDone()
+
+	// images are extracted before the tasks are written, since the tasks
+	// must refer to any credentials externalized from them
+	var images []extractedImage
+	if c.ExtractImages {
+		images, err = extractImages(tasks)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.ExternalizeRegistryCredentials {
+			externalized.RegistryCredentials = externalizeRegistryCredentials(images, tasks)
+		}
+	}
+
+	if err := c.Context.Err(); err != nil {
+		return nil, err
+	}
+
+	tasksDone := c.phase("tasks")
+
+	scriptNames := map[string]string{}
+	if c.DedupeScripts {
+		scriptNames, err = dedupeScripts(scripts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, script := range scripts {
+		name := script.Name
+		if canonical, found := scriptNames[name]; found {
+			name = canonical
+		}
+
+		err := c.write(GeneratedFile{
+			Path:    filepath.Join(scriptsPath, taskNamespace, name),
+			Kind:    "script",
+			Source:  script.Source,
+			Payload: script.Payload,
+			Mode:    0644,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to sync script: %w", err)
+		}
+	}
+
+	// configs of the converted tasks as written, by the name steps run them by
+	convertedTaskConfigs := map[string]atc.TaskConfig{}
+
+	// the images extracted from each task, by its index
+	taskImages := map[int]string{}
+	for _, image := range images {
+		for _, t := range image.taskIndexes {
+			taskImages[t] = image.Name
+		}
+	}
+
+	for i, task := range tasks {
+		if task.Script != "" {
+			name := task.Script
+			if canonical, found := scriptNames[name]; found {
+				name = canonical
+			}
+
+			task.Config.Run.Path = filepath.Join(c.ProjectName, "tasks", "scripts", taskNamespace, name)
+		}
+
+		applyTaskDefaults(&task.Config, c.Defaults)
+
+		convertedTaskConfigs[path.Join(taskNamespace, task.Name)] = task.Config
+
+		// the image_resource is kept so that the task still runs as-is, with
+		// a reference to where it was extracted to
+		var comment string
+		if image, found := taskImages[i]; found {
+			comment = "image: " + path.Join(imagesPath, image+".yml")
+		}
+
+		err := c.renderWithComment(GeneratedFile{
+			Path:   task.Path,
+			Kind:   "task",
+			Name:   task.Name,
+			Source: task.Source,
+		}, "task.tmpl", task.Config, comment)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tasksDone()
+
+	if err := c.Context.Err(); err != nil {
+		return nil, err
+	}
+
+	if c.ExtractImages {
+		imagesDone := c.phase("images")
+
+		for _, image := range images {
+			log := logrus.WithFields(logrus.Fields{
+				"name":  image.Name,
+				"tasks": image.Tasks,
+			})
+
+			log.Info("extracting image")
+
+			if len(image.Tasks) > 1 {
+				log.Info("tasks share image")
+			}
+
+			anon, err := anonymize(image.Image, false)
+			if err != nil {
+				return nil, invalidf("image '%s' of type '%s': %s", image.Name, image.Image.Type, err)
+			}
+
+			anon.ResourceName = image.Name
+
+			err = c.render(GeneratedFile{
+				Path:   filepath.Join(imagesPath, image.Name+".yml"),
+				Kind:   "image",
+				Name:   image.Name,
+				Source: strings.Join(image.Tasks, ", "),
+			}, c.Templates.resourceTemplate(image.Image.Type), anon)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		imagesDone()
+	}
+
+	config.Jobs = newJobs
+
+	err = validateGroups(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// graph the jobs as they were converted, e.g. with steps filtered out
+	graph, err := pipelineGraph(c.PipelineName, config)
+	if err != nil {
+		return nil, err
+	}
+
+	reassembled, err := reassemblePipeline(config, convertedTaskConfigs)
+	if err != nil {
+		return nil, err
+	}
+
+	// resources are still in the overview when they're not in the pipeline
+	// file, and only have files of their own when it isn't flat
+	overviewConfig := config
+	overviewResourcesPath := ""
+
+	if !c.Flat {
+		overviewResourcesPath = resourcesPath
+		config.Resources = nil
+		config.ResourceTypes = nil
+	}
+
+	if err := c.Context.Err(); err != nil {
+		return nil, err
+	}
+
+	pipelineDone := c.phase("render pipeline")
+
+	pipelinePath := filepath.Join(pipelinesPath, c.PipelineName+".yml")
+	err = c.render(GeneratedFile{
+		Path:   pipelinePath,
+		Kind:   "pipeline",
+		Name:   c.PipelineName,
+		Source: c.ConfigSource,
+	}, "pipeline.tmpl", config)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelineDone()
+
+	err = c.result.Stats.addPipeline(overviewConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	c.result.Graph.Pipelines = append(c.result.Graph.Pipelines, graph)
+	c.result.Overview.addPipeline(c.PipelineName, overviewConfig, tasks, overviewResourcesPath)
+
+	c.result.Pipelines = append(c.result.Pipelines, Pipeline{
+		Name:   c.PipelineName,
+		Config: reassembled,
+		Source: c.ConfigSource,
+	})
+
+	return &externalized, nil
+}
+
+// prepareConfig validates the config and applies the renames, exclusions,
+// and source changes given by the options.
+func (c *converter) prepareConfig(config *PipelineConfig) error {
+	err := validateNames(*config)
+	if err != nil {
+		return err
+	}
+
+	err = renameResources(config, c.RenameResources)
+	if err != nil {
+		return err
+	}
+
+	err = excludeJobs(config, c.ExcludeJobs)
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range reconcileGroups(config, c.ExcludeJobs) {
+		c.warn(warning.Fields, warning.Message)
+	}
+
+	rewriteSources(config, c.RewriteSources)
+
+	return overrideSources(config, c.SetSources)
+}
+
+// path of the vars file the webhook tokens are externalized to
+var webhookTokensPath = filepath.Join("vars", "webhooks.yml")
+
+// path of the vars file the credentials of extracted images are
+// externalized to
+var registryCredentialsPath = filepath.Join("vars", "registry.yml")
+
+// setPipelinesScriptPath is where --emit-fly-script writes the script
+var setPipelinesScriptPath = filepath.Join("scripts", "set-pipelines.sh")
+
+// writeProjectFiles writes the files which cover every converted pipeline:
+// project.yml, the set-pipelines script, and the externalized webhook tokens
+// and registry credentials. The script loads each pipeline's vars files.
+func (c *converter) writeProjectFiles(pipelines []string, externalized externalizedVars, varsFiles map[string][]string) error {
+	if !c.Flat {
+		projectConfig := ProjectConfig{
+			Name: c.ProjectName,
+		}
+
+		for _, name := range pipelines {
+			projectConfig.Plan = append(projectConfig.Plan, map[string]string{
+				"set_pipeline": name,
+			})
+		}
+
+		err := c.render(GeneratedFile{
+			Path: "project.yml",
+			Kind: "project",
+			Name: c.ProjectName,
+		}, "project.tmpl", projectConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(externalized.WebhookTokens) > 0 {
+		payload, err := yaml.Marshal(externalized.WebhookTokens)
+		if err != nil {
+			return err
+		}
+
+		err = c.write(GeneratedFile{
+			Path:    webhookTokensPath,
+			Kind:    "vars",
+			Payload: convertLineEndings(payload, c.LineEndings),
+			Mode:    0600,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write webhook tokens: %w", err)
+		}
+	}
+
+	if len(externalized.RegistryCredentials) > 0 {
+		payload, err := yaml.Marshal(externalized.RegistryCredentials)
+		if err != nil {
+			return err
+		}
+
+		err = c.write(GeneratedFile{
+			Path:    registryCredentialsPath,
+			Kind:    "vars",
+			Payload: convertLineEndings(payload, c.LineEndings),
+			Mode:    0600,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write registry credentials: %w", err)
+		}
+	}
+
+	if c.EmitFlyScript {
+		pipelinePaths := map[string]string{}
+		for _, name := range pipelines {
+			pipelinePaths[name] = filepath.Join("pipelines", name+".yml")
+		}
+
+		err := c.write(GeneratedFile{
+			Path:    setPipelinesScriptPath,
+			Kind:    "fly-script",
+			Payload: setPipelinesScript(pipelinePaths, varsFiles),
+			Mode:    0755,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write set-pipelines script: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// warn logs the warning and records it in the result.
+func (c *converter) warn(fields logrus.Fields, message string) {
+	logrus.WithFields(fields).Warn(message)
+
+	c.result.Warnings = append(c.result.Warnings, Warning{
+		Fields:  fields,
+		Message: message,
+	})
+}
+
+// phase starts timing the named phase of converting the current pipeline,
+// returning a func which stops.
+func (c *converter) phase(name string) func() {
+	if c.PipelineName != "" {
+		name = c.PipelineName + ": " + name
+	}
+
+	return c.result.Trace.phase(name)
+}
+
+// render pretty-prints the value with the named template and writes it to the
+// file, so long as it's equivalent to the value.
+func (c *converter) render(file GeneratedFile, name string, val interface{}) error {
+	return c.renderWithComment(file, name, val, "")
+}
+
+// renderWithComment renders the file as with render, starting it with the
+// comment if one is given.
+func (c *converter) renderWithComment(file GeneratedFile, name string, val interface{}, comment string) error {
+	err := c.renderFile(file, name, val, comment)
+	if err != nil {
+		return fmt.Errorf("%s: rendering %s '%s' with %s: %w", file.Path, file.Kind, file.Name, name, err)
+	}
+
+	return nil
+}
+
+func (c *converter) renderFile(file GeneratedFile, name string, val interface{}, comment string) error {
+	payload, err := c.prettyPrint(file, name, val)
+	if err != nil {
+		return err
+	}
+
+	if comment != "" {
+		payload = c.addComment(payload, comment)
+	}
+
+	file.Payload = payload
+	file.Mode = 0644
+
+	err = c.write(file)
+	if err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+
+	return nil
+}
+
+// prettyPrint renders the value with the named template, verifying that the
+// result is equivalent to the value.
+func (c *converter) prettyPrint(file GeneratedFile, name string, val interface{}) ([]byte, error) {
+	start := time.Now()
+
+	payload, err := yaml.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+
+	prettyPayload := new(bytes.Buffer)
+	if c.Templates != nil {
+		var data interface{} = val
+		if c.TemplateContext && c.Templates.custom[name] {
+			data = TemplateContext{
+				Project:  c.ProjectName,
+				Pipeline: c.PipelineName,
+				Kind:     file.Kind,
+				Name:     file.Name,
+				Value:    val,
+				Extra:    c.TemplateData.Value,
+			}
+		}
+
+		err = c.Templates.tmpl.ExecuteTemplate(prettyPayload, name, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute template: %s", err)
+		}
+	} else {
+		_, err = prettyPayload.Write(payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.restyled() {
+		restyled, err := restyleYAML(prettyPayload.Bytes(), c.yamlIndent(), c.YAMLIndentSequences, c.SortKeys)
+		if err != nil {
+			return nil, fmt.Errorf("template rendered invalid YAML: %s", err)
+		}
+
+		prettyPayload = bytes.NewBuffer(restyled)
+	}
+
+	if command := c.postRenderCmd(file.Kind); command != "" {
+		processed, err := pipeThrough(c.Context, command, nil, prettyPayload.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("post-render command '%s' failed: %w", command, err)
+		}
+
+		prettyPayload = bytes.NewBuffer(processed)
+	}
+
+	rendered := time.Now()
+
+	// verify that the pretty-printed value is equivalent
+	var x, y interface{}
+	err = yaml.Unmarshal(prettyPayload.Bytes(), &x)
+	if err != nil {
+		return nil, fmt.Errorf("template rendered invalid YAML: %s", err)
+	}
+
+	err = yaml.Unmarshal(payload, &y)
+	if err != nil {
+		return nil, fmt.Errorf("template rendered invalid YAML: %s", err)
+	}
+
+	if !reflect.DeepEqual(x, y) && !decodesTo(prettyPayload.Bytes(), val) {
+		return nil, fmt.Errorf("pretty-printed value not equvalent to ugly-printed value:\n\n%s\n\npretty value:\n\n%s", payload, prettyPayload.Bytes())
+	}
+
+	c.result.Trace.addFile(file.Path, rendered.Sub(start), time.Since(rendered))
+
+	pretty := convertLineEndings(prettyPayload.Bytes(), c.LineEndings)
+
+	// added once the value's been verified so that it's never compared, and
+	// only if a template or command didn't start the file with one already
+	marker := convertLineEndings([]byte(documentMarker), c.LineEndings)
+	if c.YAMLDocumentMarker && !bytes.HasPrefix(pretty, marker) {
+		return append(marker, pretty...), nil
+	}
+
+	return pretty, nil
+}
+
+// the start of a YAML document
+const documentMarker = "---\n"
+
+// addComment adds the comment to the top of the rendered file, after its
+// document marker if it has one.
+func (c *converter) addComment(payload []byte, comment string) []byte {
+	line := convertLineEndings([]byte("# "+comment+"\n"), c.LineEndings)
+
+	marker := convertLineEndings([]byte(documentMarker), c.LineEndings)
+	if bytes.HasPrefix(payload, marker) {
+		return append(append(marker, line...), payload[len(marker):]...)
+	}
+
+	return append(line, payload...)
+}
+
+// restyled reports whether rendered files should be re-encoded in a style
+// other than the default.
+func (c *converter) restyled() bool {
+	return c.yamlIndent() != defaultYAMLIndent || c.YAMLIndentSequences || c.SortKeys
+}
+
+func (c *converter) yamlIndent() int {
+	if c.YAMLIndent == 0 {
+		return defaultYAMLIndent
+	}
+
+	return c.YAMLIndent
+}
+
+// write passes the file to the writer, if any, and adds it to the result.
+// A file generated more than once is only added the first time, but is still
+// written each time so that the writer can catch conflicting content. A file
+// generated differently by two pipelines is an error.
+func (c *converter) write(file GeneratedFile) error {
+	if c.pipeline != "" {
+		file.Pipelines = []string{c.pipeline}
+	}
+
+	existing := -1
+	for i, written := range c.result.Files {
+		if written.Path == file.Path {
+			existing = i
+			break
+		}
+	}
+
+	if existing != -1 && c.pipeline != "" {
+		shared := c.result.Files[existing]
+		if len(shared.Pipelines) > 0 && !containsString(shared.Pipelines, c.pipeline) && !bytes.Equal(shared.Payload, file.Payload) {
+			hint := ""
+			if file.Kind == "task" || file.Kind == "script" {
+				hint = "; pass --namespace-tasks to give each pipeline its own tasks"
+			}
+
+			return invalidf("%s: generated differently for pipelines '%s' and '%s'%s", file.Path, shared.Pipelines[0], c.pipeline, hint)
+		}
+	}
+
+	if c.Writer != nil {
+		err := c.Writer.WriteFile(file)
+		if err != nil {
+			return err
+		}
+	}
+
+	if existing != -1 {
+		shared := &c.result.Files[existing]
+		if c.pipeline != "" && !containsString(shared.Pipelines, c.pipeline) {
+			shared.Pipelines = append(shared.Pipelines, c.pipeline)
+		}
+
+		return nil
+	}
+
+	c.result.Files = append(c.result.Files, file)
+
+	return nil
+}
+
+func containsString(list []string, str string) bool {
+	for _, s := range list {
+		if s == str {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decodesTo checks whether the payload decodes to the given value when
+// decoded into the value's own type. This treats e.g. 'on' and '"on"' as
+// equal for string fields, where the value is a string either way.
+func decodesTo(payload []byte, val interface{}) bool {
+	decoded := reflect.New(reflect.TypeOf(val))
+
+	err := yaml.UnmarshalStrict(payload, decoded.Interface())
+	if err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(decoded.Elem().Interface(), val)
+}
+
+// anonymize copies the fields of a resource, resource type, or image into an
+// AnonymousResourceConfig, leaving its name out unless keepName is set.
+func anonymize(resource interface{}, keepName bool) (AnonymousResourceConfig, error) {
+	var anon AnonymousResourceConfig
+	switch res := resource.(type) {
+	case atc.ResourceConfig:
+		anon = AnonymousResourceConfig{
+			Name:         res.Name,
+			Public:       res.Public,
+			WebhookToken: res.WebhookToken,
+			Type:         res.Type,
+			Source:       res.Source,
+			CheckEvery:   res.CheckEvery,
+			CheckTimeout: res.CheckTimeout,
+			Tags:         res.Tags,
+			Version:      res.Version,
+			Icon:         res.Icon,
+		}
+
+	case atc.ResourceType:
+		anon = AnonymousResourceConfig{
+			Name:                 res.Name,
+			Type:                 res.Type,
+			Source:               res.Source,
+			Privileged:           res.Privileged,
+			CheckEvery:           res.CheckEvery,
+			Tags:                 res.Tags,
+			Params:               res.Params,
+			UniqueVersionHistory: res.UniqueVersionHistory,
+		}
+
+	case atc.ImageResource:
+		anon = AnonymousResourceConfig{
+			Type:   res.Type,
+			Source: res.Source,
+		}
+
+		if res.Params != nil {
+			anon.Params = *res.Params
+		}
+
+		if res.Version != nil {
+			anon.Version = *res.Version
+		}
+
+	default:
+		return AnonymousResourceConfig{}, fmt.Errorf("cannot anonymize %T", resource)
+	}
+
+	_, err := yaml.Marshal(anon.Source)
+	if err != nil {
+		return AnonymousResourceConfig{}, fmt.Errorf("invalid source: %s", err)
+	}
+
+	anon.ResourceName = anon.Name
+
+	if !keepName {
+		anon.Name = ""
+	}
+
+	return anon, nil
+}
+
+// setPipelinesScript generates a script which sets each pipeline, given as a
+// mapping from pipeline name to config path relative to the project, loading
+// the vars files generated for it. Any arguments to the script (e.g. -l
+// vars.yml) are passed along to fly.
+func setPipelinesScript(pipelines map[string]string, varsFiles map[string][]string) []byte {
+	var names []string
+	for name := range pipelines {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	script := new(bytes.Buffer)
+	fmt.Fprintln(script, "#!/bin/sh")
+	fmt.Fprintln(script)
+	fmt.Fprintln(script, "set -e -u")
+	fmt.Fprintln(script)
+	fmt.Fprintln(script, `cd "$(dirname "$0")/.."`)
+	fmt.Fprintln(script)
+
+	for _, name := range names {
+		var loadVars string
+		for _, path := range varsFiles[name] {
+			loadVars += " -l " + path
+		}
+
+		fmt.Fprintf(script, "fly -t \"${TARGET:?}\" set-pipeline -p %s -c %s%s \"$@\"\n", name, pipelines[name], loadVars)
+	}
+
+	return script.Bytes()
+}
+
+// normalizeScript trims trailing whitespace from each line and ensures the
+// script ends with exactly one newline. Line endings are left as they are.
+func normalizeScript(payload []byte) []byte {
+	lines := strings.Split(string(payload), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimRight(line, " \t\r")
+		if strings.HasSuffix(line, "\r") {
+			trimmed += "\r"
+		}
+
+		lines[i] = trimmed
+	}
+
+	for len(lines) > 0 && strings.TrimSuffix(lines[len(lines)-1], "\r") == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		return []byte{}
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// dedupeScripts groups scripts by content and maps each script name to the
+// lexicographically smallest name sharing its content, so that re-runs choose
+// the same canonical name. Scripts of the same name with different content
+// can't be told apart by name, so they're an error.
+func dedupeScripts(scripts []convertedScript) (map[string]string, error) {
+	var hashes []string
+	names := map[string][]string{}
+
+	// the hash and source of the first script seen with each name
+	hashByName := map[string]string{}
+	sourceByName := map[string]string{}
+
+	for _, script := range scripts {
+		hash := fmt.Sprintf("%x", sha256.Sum256(script.Payload))
+		if existing, found := hashByName[script.Name]; found {
+			if existing != hash {
+				return nil, invalidf("cannot dedupe scripts: %s and %s are both named '%s' but differ", sourceByName[script.Name], script.Source, script.Name)
+			}
+
+			continue
+		}
+
+		hashByName[script.Name] = hash
+		sourceByName[script.Name] = script.Source
+
+		if _, found := names[hash]; !found {
+			hashes = append(hashes, hash)
+		}
+
+		names[hash] = append(names[hash], script.Name)
+	}
+
+	canonical := map[string]string{}
+	for _, hash := range hashes {
+		sort.Strings(names[hash])
+
+		for _, name := range names[hash] {
+			canonical[name] = names[hash][0]
+
+			if name != names[hash][0] {
+				logrus.WithFields(logrus.Fields{
+					"script":    name,
+					"canonical": names[hash][0],
+				}).Info("deduplicating script")
+			}
+		}
+	}
+
+	return canonical, nil
+}
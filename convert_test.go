@@ -0,0 +1,650 @@
+package pipe2proj
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/concourse/concourse/atc"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	// warnings are checked through the result instead
+	logrus.SetOutput(io.Discard)
+
+	os.Exit(m.Run())
+}
+
+// recordingWriter records each file it's given, in order.
+type recordingWriter struct {
+	files []GeneratedFile
+}
+
+func (writer *recordingWriter) WriteFile(file GeneratedFile) error {
+	writer.files = append(writer.files, file)
+	return nil
+}
+
+// readFixture reads a file from testdata.
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	payload, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return payload
+}
+
+// generatedFile returns the payload of the file generated at the path.
+func generatedFile(t *testing.T, result *Result, path string) []byte {
+	t.Helper()
+
+	for _, file := range result.Files {
+		if file.Path == path {
+			return file.Payload
+		}
+	}
+
+	var paths []string
+	for _, file := range result.Files {
+		paths = append(paths, file.Path)
+	}
+
+	t.Fatalf("no file generated at %s; generated %v", path, paths)
+
+	return nil
+}
+
+// convertedPipeline parses the pipeline file generated for the pipeline.
+func convertedPipeline(t *testing.T, result *Result, name string) PipelineConfig {
+	t.Helper()
+
+	config, _, err := parsePipeline(generatedFile(t, result, filepath.Join("pipelines", name+".yml")), "refuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return config
+}
+
+// ciArtifact is a task artifact with a unit task which runs a script.
+var ciArtifact = fstest.MapFS{
+	"ci/unit.yml": {Data: []byte(`platform: linux
+image_resource:
+  type: registry-image
+  source: {repository: golang, tag: "1.21"}
+inputs:
+- name: repo
+run:
+  path: repo/ci/unit.sh
+`)},
+	"ci/unit.sh": {Data: []byte("#!/bin/sh\ngo test ./...\n")},
+}
+
+func TestDedupeScripts(t *testing.T) {
+	for _, test := range []struct {
+		title     string
+		scripts   []convertedScript
+		canonical map[string]string
+		invalid   bool
+	}{
+		{
+			title: "identical content under different names",
+			scripts: []convertedScript{
+				{Name: "test.sh", Source: "ci/tasks/test.sh", Payload: []byte("make test\n")},
+				{Name: "check.sh", Source: "ci/tasks/check.sh", Payload: []byte("make test\n")},
+				{Name: "build.sh", Source: "ci/tasks/build.sh", Payload: []byte("make\n")},
+			},
+			canonical: map[string]string{
+				"check.sh": "check.sh",
+				"test.sh":  "check.sh",
+				"build.sh": "build.sh",
+			},
+		},
+		{
+			title: "the same script used by several tasks",
+			scripts: []convertedScript{
+				{Name: "test.sh", Source: "ci/tasks/test.sh", Payload: []byte("make test\n")},
+				{Name: "test.sh", Source: "ci/tasks/test.sh", Payload: []byte("make test\n")},
+			},
+			canonical: map[string]string{
+				"test.sh": "test.sh",
+			},
+		},
+		{
+			title: "different content under the same name",
+			scripts: []convertedScript{
+				{Name: "test.sh", Source: "ci/tasks/test.sh", Payload: []byte("make test\n")},
+				{Name: "a.sh", Source: "ci/tasks/a.sh", Payload: []byte("make\n")},
+				{Name: "test.sh", Source: "other/test.sh", Payload: []byte("go test ./...\n")},
+				{Name: "b.sh", Source: "ci/tasks/b.sh", Payload: []byte("make\n")},
+			},
+			invalid: true,
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			canonical, err := dedupeScripts(test.scripts)
+			if test.invalid {
+				var invalid ValidationError
+				if !errors.As(err, &invalid) {
+					t.Fatalf("expected a validation error, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(canonical, test.canonical) {
+				t.Errorf("expected %v, got %v", test.canonical, canonical)
+			}
+		})
+	}
+}
+
+func TestConvertPreservesJobFields(t *testing.T) {
+	payload := readFixture(t, "job-fields.yml")
+
+	result, err := Convert(Options{
+		ProjectName:   "ci",
+		PipelineName:  "main",
+		Config:        payload,
+		TaskArtifacts: map[string]fs.FS{"repo": ciArtifact},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original, _, err := parsePipeline(payload, "refuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	converted := convertedPipeline(t, result, "main")
+	if len(converted.Jobs) != 1 {
+		t.Fatalf("expected 1 job, got %d", len(converted.Jobs))
+	}
+
+	job := converted.Jobs[0]
+
+	// the task in the plan is the only thing converted
+	expectedPlan := atc.PlanSequence{
+		original.Jobs[0].Plan[0],
+		{Task: "unit"},
+	}
+
+	if !reflect.DeepEqual(job.Plan, expectedPlan) {
+		t.Errorf("expected plan %#v, got %#v", expectedPlan, job.Plan)
+	}
+
+	job.Plan = nil
+	expected := original.Jobs[0]
+	expected.Plan = nil
+
+	if !reflect.DeepEqual(job, expected) {
+		t.Errorf("job fields changed:\n\nexpected %#v\n\ngot %#v", expected, job)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	writer := &recordingWriter{}
+
+	result, err := Convert(Options{
+		ProjectName:  "ci",
+		PipelineName: "main",
+		Config: []byte(`
+resources:
+- name: repo
+  type: git
+  source: {uri: https://example.com/repo.git}
+
+jobs:
+- name: unit
+  plan:
+  - get: repo
+    trigger: true
+  - task: unit
+    file: repo/ci/unit.yml
+`),
+		ConfigSource:  "main.yml",
+		TaskArtifacts: map[string]fs.FS{"repo": ciArtifact},
+		Writer:        writer,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []GeneratedFile{
+		{
+			Path:      "resources/repo.yml",
+			Kind:      "resource",
+			Name:      "repo",
+			Source:    "repo",
+			Pipelines: []string{"main"},
+			Mode:      0644,
+			Payload: []byte(`---
+type: git
+
+source:
+  uri: https://example.com/repo.git
+`),
+		},
+		{
+			Path:      "tasks/scripts/unit.sh",
+			Kind:      "script",
+			Source:    "repo/ci/unit.sh",
+			Pipelines: []string{"main"},
+			Mode:      0644,
+			Payload:   []byte("#!/bin/sh\ngo test ./...\n"),
+		},
+		{
+			Path:      "tasks/unit.yml",
+			Kind:      "task",
+			Name:      "unit",
+			Source:    "repo/ci/unit.yml",
+			Pipelines: []string{"main"},
+			Mode:      0644,
+			Payload: []byte(`---
+platform: linux
+
+image_resource:
+  type: registry-image
+  source:
+    repository: golang
+    tag: "1.21"
+
+inputs:
+- name: ci
+- name: repo
+
+run:
+  path: ci/tasks/scripts/unit.sh
+`),
+		},
+		{
+			Path:      "pipelines/main.yml",
+			Kind:      "pipeline",
+			Name:      "main",
+			Source:    "main.yml",
+			Pipelines: []string{"main"},
+			Mode:      0644,
+			Payload: []byte(`---
+jobs:
+- name: unit
+  plan:
+  - get: repo
+    trigger: true
+  - task: unit
+
+`),
+		},
+		{
+			Path: "project.yml",
+			Kind: "project",
+			Name: "ci",
+			Mode: 0644,
+			Payload: []byte(`---
+name: ci
+
+plan:
+- set_pipeline: main
+`),
+		},
+	}
+
+	if len(writer.files) != len(expected) {
+		t.Fatalf("expected %d files to be written, got %d", len(expected), len(writer.files))
+	}
+
+	for i, file := range writer.files {
+		if !reflect.DeepEqual(file, expected[i]) {
+			t.Errorf("file %d: expected %s:\n\n%s\n\ngot %s:\n\n%s", i, expected[i].Path, expected[i].Payload, file.Path, file.Payload)
+		}
+	}
+
+	if !reflect.DeepEqual(result.Files, writer.files) {
+		t.Error("expected the result to have the files as written")
+	}
+
+	if len(result.Warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", result.Warnings)
+	}
+}
+
+func TestConvertWriterError(t *testing.T) {
+	_, err := Convert(Options{
+		ProjectName:   "ci",
+		PipelineName:  "main",
+		Config:        readFixture(t, "job-fields.yml"),
+		TaskArtifacts: map[string]fs.FS{"repo": ciArtifact},
+		Writer:        failingWriter{},
+	})
+	if !errors.Is(err, errWriteFailed) {
+		t.Fatalf("expected the writer's error, got %v", err)
+	}
+}
+
+var errWriteFailed = errors.New("write failed")
+
+type failingWriter struct{}
+
+func (failingWriter) WriteFile(GeneratedFile) error {
+	return errWriteFailed
+}
+
+// cancellingWriter cancels the conversion once the first file is written.
+type cancellingWriter struct {
+	recordingWriter
+
+	cancel context.CancelFunc
+}
+
+func (writer *cancellingWriter) WriteFile(file GeneratedFile) error {
+	writer.cancel()
+	return writer.recordingWriter.WriteFile(file)
+}
+
+func TestConvertCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	writer := &cancellingWriter{cancel: cancel}
+
+	_, err := Convert(Options{
+		Context:       ctx,
+		ProjectName:   "ci",
+		PipelineName:  "main",
+		Config:        readFixture(t, "job-fields.yml"),
+		TaskArtifacts: map[string]fs.FS{"repo": ciArtifact},
+		Writer:        writer,
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the conversion to be cancelled, got %v", err)
+	}
+
+	// the resources are written first, and nothing after
+	var paths []string
+	for _, file := range writer.files {
+		paths = append(paths, file.Path)
+	}
+
+	if !reflect.DeepEqual(paths, []string{"resources/repo.yml"}) {
+		t.Errorf("expected only resources/repo.yml to be written, got %v", paths)
+	}
+}
+
+func TestConvertCancelledStepFilter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err := Convert(Options{
+		Context:       ctx,
+		ProjectName:   "ci",
+		PipelineName:  "main",
+		Config:        readFixture(t, "job-fields.yml"),
+		TaskArtifacts: map[string]fs.FS{"repo": ciArtifact},
+		StepFilter:    "exec sleep 10",
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the conversion to time out, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the step filter to be killed, but it took %s", elapsed)
+	}
+}
+
+func TestConvertMissingTask(t *testing.T) {
+	_, err := Convert(Options{
+		ProjectName:   "ci",
+		PipelineName:  "main",
+		Config:        readFixture(t, "job-fields.yml"),
+		TaskArtifacts: map[string]fs.FS{"repo": fstest.MapFS{}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "plan[1]: loading task") {
+		t.Fatalf("expected the missing task to be reported, got %v", err)
+	}
+}
+
+func TestConvertPreservesGetSteps(t *testing.T) {
+	payload := readFixture(t, "get-steps.yml")
+
+	// as written in the fixture, in the order fields are marshalled
+	getSteps := `  - get: repo
+    passed:
+    - job-a
+    - job-b
+    trigger: true
+    params:
+      depth: 1
+    version:
+      ref: abcdef
+  - get: pinned
+    passed:
+    - job-a
+    resource: repo
+    params:
+      submodules: none
+    version: every
+`
+
+	if !strings.Contains(string(payload), getSteps) {
+		t.Fatal("fixture doesn't have the get steps as expected")
+	}
+
+	result, err := Convert(Options{
+		ProjectName:   "ci",
+		PipelineName:  "main",
+		Config:        payload,
+		TaskArtifacts: map[string]fs.FS{"repo": ciArtifact},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pipeline := generatedFile(t, result, "pipelines/main.yml")
+	if !strings.Contains(string(pipeline), getSteps) {
+		t.Errorf("expected the get steps to be written as they were:\n\n%s\n\ngot:\n\n%s", getSteps, pipeline)
+	}
+
+	original, _, err := parsePipeline(payload, "refuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	converted := convertedPipeline(t, result, "main")
+	for i, step := range converted.Jobs[2].Plan[:2] {
+		if !reflect.DeepEqual(step, original.Jobs[2].Plan[i]) {
+			t.Errorf("get step %d: expected %#v, got %#v", i, original.Jobs[2].Plan[i], step)
+		}
+	}
+}
+
+func TestConvertSingleDo(t *testing.T) {
+	result, err := Convert(Options{
+		ProjectName:   "ci",
+		PipelineName:  "main",
+		Config:        readFixture(t, "single-do.yml"),
+		TaskArtifacts: map[string]fs.FS{"repo": ciArtifact},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	converted := convertedPipeline(t, result, "main")
+
+	expected := map[string]atc.PlanSequence{
+		"unit": {
+			{Do: &atc.PlanSequence{
+				{Get: "repo", Trigger: true},
+				{Task: "unit"},
+			}},
+		},
+		"nested": {
+			{Do: &atc.PlanSequence{
+				{Do: &atc.PlanSequence{
+					{Get: "repo"},
+					{Task: "unit"},
+				}},
+			}},
+		},
+	}
+
+	if len(converted.Jobs) != len(expected) {
+		t.Fatalf("expected %d jobs, got %d", len(expected), len(converted.Jobs))
+	}
+
+	for _, job := range converted.Jobs {
+		if !reflect.DeepEqual(job.Plan, expected[job.Name]) {
+			t.Errorf("job %s: expected plan %#v, got %#v", job.Name, expected[job.Name], job.Plan)
+		}
+	}
+}
+
+func TestConvertJobHookTask(t *testing.T) {
+	artifact := fstest.MapFS{
+		"ci/unit.yml": ciArtifact["ci/unit.yml"],
+		"ci/unit.sh":  ciArtifact["ci/unit.sh"],
+		"ci/notify.yml": {Data: []byte(`platform: linux
+image_resource:
+  type: registry-image
+  source: {repository: alpine}
+run:
+  path: echo
+  args: [failed]
+`)},
+	}
+
+	result, err := Convert(Options{
+		ProjectName:   "ci",
+		PipelineName:  "main",
+		Config:        readFixture(t, "job-hooks.yml"),
+		TaskArtifacts: map[string]fs.FS{"repo": artifact},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range result.Files {
+		if file.Path == "tasks/notify.yml" && file.Source != "repo/ci/notify.yml" {
+			t.Errorf("expected tasks/notify.yml to be converted from repo/ci/notify.yml, got %s", file.Source)
+		}
+	}
+
+	expected := `---
+platform: linux
+
+image_resource:
+  type: registry-image
+  source:
+    repository: alpine
+
+run:
+  path: echo
+  args:
+  - failed
+`
+
+	if task := string(generatedFile(t, result, "tasks/notify.yml")); task != expected {
+		t.Errorf("expected tasks/notify.yml:\n\n%s\n\ngot:\n\n%s", expected, task)
+	}
+
+	pipeline := string(generatedFile(t, result, "pipelines/main.yml"))
+	if strings.Contains(pipeline, "notify.yml") {
+		t.Errorf("expected the hook's file reference to be rewritten:\n\n%s", pipeline)
+	}
+
+	job := convertedPipeline(t, result, "main").Jobs[0]
+	if job.Failure == nil || !reflect.DeepEqual(*job.Failure, atc.PlanConfig{Task: "notify"}) {
+		t.Errorf("expected the job's on_failure to become 'task: notify', got %#v", job.Failure)
+	}
+}
+
+func TestConvertEmitFlyScript(t *testing.T) {
+	artifact := fstest.MapFS{
+		"ci/ship.yml": {Data: []byte(`platform: linux
+image_resource:
+  type: registry-image
+  source:
+    repository: registry.example.com/shipper
+    username: ci-bot
+    password: literal-password
+run: {path: ship}
+`)},
+	}
+
+	result, err := Convert(Options{
+		ProjectName: "ci",
+		Pipelines: []Pipeline{
+			{
+				Name: "release",
+				Config: []byte(`
+jobs:
+- name: ship
+  plan:
+  - task: ship
+    file: repo/ci/ship.yml
+`),
+			},
+			{
+				Name: "main",
+				Config: []byte(`
+resources:
+- name: repo
+  type: git
+  webhook_token: literal-token
+  source: {uri: https://example.com/repo.git}
+
+jobs:
+- name: unit
+  plan:
+  - get: repo
+`),
+			},
+		},
+		TaskArtifacts:                  map[string]fs.FS{"repo": artifact},
+		ExtractImages:                  true,
+		ExternalizeRegistryCredentials: true,
+		ExternalizeWebhookTokens:       true,
+		EmitFlyScript:                  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `#!/bin/sh
+
+set -e -u
+
+cd "$(dirname "$0")/.."
+
+fly -t "${TARGET:?}" set-pipeline -p main -c pipelines/main.yml -l vars/webhooks.yml "$@"
+fly -t "${TARGET:?}" set-pipeline -p release -c pipelines/release.yml -l vars/registry.yml "$@"
+`
+
+	script := string(generatedFile(t, result, "scripts/set-pipelines.sh"))
+	if script != expected {
+		t.Errorf("expected scripts/set-pipelines.sh:\n\n%s\n\ngot:\n\n%s", expected, script)
+	}
+
+	for _, file := range result.Files {
+		if file.Path == "scripts/set-pipelines.sh" && file.Mode != 0755 {
+			t.Errorf("expected %s to be executable, got %s", file.Path, file.Mode)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package pipe2proj
+
+import (
+	"path"
+	"time"
+
+	"github.com/concourse/concourse/atc"
+)
+
+// defaults Concourse uses for fields which resources and resource types
+// leave out
+const (
+	defaultCheckEvery   = "1m"
+	defaultCheckTimeout = "1h"
+)
+
+// applyResourceDefaults spells out the fields of a resource or resource type
+// which Concourse would otherwise default with "expand", or removes those set
+// to their default with "strip". Boolean fields are always left out when
+// false, so they can only be stripped.
+func applyResourceDefaults(res *AnonymousResourceConfig, mode string, resourceType bool) {
+	res.CheckEvery = applyDefault(res.CheckEvery, defaultCheckEvery, mode)
+
+	// resource types have no check timeout
+	if !resourceType {
+		res.CheckTimeout = applyDefault(res.CheckTimeout, defaultCheckTimeout, mode)
+	}
+}
+
+// applyTaskDefaults spells out the paths of the task's inputs and outputs
+// with "expand", which default to their names, or removes those which are the
+// same as their names with "strip".
+func applyTaskDefaults(task *atc.TaskConfig, mode string) {
+	// copied, as the task's config may share them with others
+	task.Inputs = append([]atc.TaskInputConfig(nil), task.Inputs...)
+	task.Outputs = append([]atc.TaskOutputConfig(nil), task.Outputs...)
+
+	for i, input := range task.Inputs {
+		task.Inputs[i].Path = applyPathDefault(input.Path, input.Name, mode)
+	}
+
+	for i, output := range task.Outputs {
+		task.Outputs[i].Path = applyPathDefault(output.Path, output.Name, mode)
+	}
+}
+
+// applyDefault applies the mode to a duration field with the given default.
+// Values which aren't durations, e.g. 'never', are left alone.
+func applyDefault(value string, def string, mode string) string {
+	switch mode {
+	case "expand":
+		if value == "" {
+			return def
+		}
+	case "strip":
+		if equalDurations(value, def) {
+			return ""
+		}
+	}
+
+	return value
+}
+
+func applyPathDefault(value string, name string, mode string) string {
+	switch mode {
+	case "expand":
+		if value == "" {
+			return name
+		}
+	case "strip":
+		if value != "" && path.Clean(value) == name {
+			return ""
+		}
+	}
+
+	return value
+}
+
+// equalDurations reports whether both strings are durations of the same
+// length, e.g. '60s' and '1m'.
+func equalDurations(a string, b string) bool {
+	x, err := time.ParseDuration(a)
+	if err != nil {
+		return false
+	}
+
+	y, err := time.ParseDuration(b)
+	if err != nil {
+		return false
+	}
+
+	return x == y
+}
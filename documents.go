@@ -0,0 +1,188 @@
+package pipe2proj
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/concourse/concourse/atc"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// keys which only a pipeline has, to tell a second pipeline from vars
+var pipelineKeys = []string{"groups", "resources", "resource_types", "jobs"}
+
+// parsePipeline decodes the pipeline config. It may be followed by further
+// YAML documents of vars, e.g. the vars it's usually set with, which are
+// returned to be interpolated into task file paths; later documents take
+// precedence. A later document which looks like a pipeline is an error, e.g.
+// from concatenating exports, unless multiDoc is "concat", in which case its
+// groups, resources, resource types, and jobs are appended to the first.
+func parsePipeline(payload []byte, multiDoc string) (PipelineConfig, atc.Source, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(payload))
+
+	var config PipelineConfig
+	err := decoder.Decode(&config)
+	if err != nil && err != io.EOF {
+		return PipelineConfig{}, nil, invalidf("unmarshal: %s", explainVarTypeError(payload, err))
+	}
+
+	vars := atc.Source{}
+	for doc := 2; ; doc++ {
+		var docVars map[string]interface{}
+		err := decoder.Decode(&docVars)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return PipelineConfig{}, nil, invalidf("unmarshal: document %d: %s", doc, err)
+		}
+
+		if key, found := pipelineKey(docVars); found {
+			if multiDoc != "concat" {
+				return PipelineConfig{}, nil, invalidf("document %d has '%s' like a pipeline; a file can only have one pipeline, followed by vars, unless --multi-doc=concat is given to combine them", doc, key)
+			}
+
+			err := concatPipeline(&config, docVars, doc)
+			if err != nil {
+				return PipelineConfig{}, nil, err
+			}
+
+			continue
+		}
+
+		for k, v := range docVars {
+			vars[k] = v
+		}
+	}
+
+	return config, vars, nil
+}
+
+// pipelineKey returns the first key of the document which only a pipeline
+// would have, if any.
+func pipelineKey(doc map[string]interface{}) (string, bool) {
+	for _, key := range pipelineKeys {
+		if _, found := doc[key]; found {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+// concatPipeline appends the groups, resources, resource types, and jobs of
+// a later document to the pipeline. Their names can't already be taken by an
+// earlier document.
+func concatPipeline(config *PipelineConfig, doc map[string]interface{}, docNum int) error {
+	payload, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	var docConfig PipelineConfig
+	err = yaml.Unmarshal(payload, &docConfig)
+	if err != nil {
+		return invalidf("unmarshal: document %d: %s", docNum, err)
+	}
+
+	var dupes []string
+	for _, group := range docConfig.Groups {
+		if _, _, found := config.Groups.Lookup(group.Name); found {
+			dupes = append(dupes, fmt.Sprintf("group '%s'", group.Name))
+		}
+	}
+
+	for _, res := range docConfig.Resources {
+		if _, found := config.Resources.Lookup(res.Name); found {
+			dupes = append(dupes, fmt.Sprintf("resource '%s'", res.Name))
+		}
+	}
+
+	for _, res := range docConfig.ResourceTypes {
+		if _, found := config.ResourceTypes.Lookup(res.Name); found {
+			dupes = append(dupes, fmt.Sprintf("resource type '%s'", res.Name))
+		}
+	}
+
+	for _, job := range docConfig.Jobs {
+		if _, found := config.Jobs.Lookup(job.Name); found {
+			dupes = append(dupes, fmt.Sprintf("job '%s'", job.Name))
+		}
+	}
+
+	if len(dupes) > 0 {
+		return invalidf("document %d repeats names from earlier documents: %s", docNum, strings.Join(dupes, ", "))
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"document": docNum,
+		"jobs":     len(docConfig.Jobs),
+	}).Info("concatenating pipeline document")
+
+	config.Groups = append(config.Groups, docConfig.Groups...)
+	config.Resources = append(config.Resources, docConfig.Resources...)
+	config.ResourceTypes = append(config.ResourceTypes, docConfig.ResourceTypes...)
+	config.Jobs = append(config.Jobs, docConfig.Jobs...)
+
+	return nil
+}
+
+// marshalPipeline encodes the pipeline config, followed by a document of vars
+// if there are any, so that parsePipeline gets back the same.
+func marshalPipeline(config PipelineConfig, vars atc.Source) ([]byte, error) {
+	payload, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vars) == 0 {
+		return payload, nil
+	}
+
+	varsPayload, err := yaml.Marshal(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append(payload, "---\n"...), varsPayload...), nil
+}
+
+// TaskFiles parses the pipeline config as Convert would, given the same
+// MultiDoc option, and lists the task config files its steps refer to, with
+// the vars and any in the file interpolated. Paths with vars which can't be
+// resolved are left out.
+func TaskFiles(payload []byte, vars atc.Source, multiDoc string) ([]string, error) {
+	config, fileVars, err := parsePipeline(payload, multiDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range vars {
+		fileVars[k] = v
+	}
+
+	var paths []string
+	for _, job := range config.Jobs {
+		err := VisitJob(job, func(_ StepPath, p atc.PlanConfig) error {
+			if p.TaskConfigPath == "" {
+				return nil
+			}
+
+			path, unresolved := interpolateVars(p.TaskConfigPath, fileVars)
+			if len(unresolved) == 0 {
+				paths = append(paths, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,284 @@
+package pipe2proj
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/concourse/concourse/atc"
+)
+
+func TestConvertVarsDocument(t *testing.T) {
+	artifact := fstest.MapFS{
+		"ci/unit.yml": ciArtifact["ci/unit.yml"],
+		"ci/unit.sh":  ciArtifact["ci/unit.sh"],
+		"release/unit.yml": {Data: []byte(`platform: linux
+image_resource:
+  type: registry-image
+  source: {repository: golang, tag: "1.21"}
+inputs:
+- name: repo
+run:
+  path: repo/release/unit.sh
+`)},
+		"release/unit.sh": {Data: []byte("#!/bin/sh\ngo test -race ./...\n")},
+	}
+
+	for _, test := range []struct {
+		title  string
+		vars   atc.Source
+		script string
+	}{
+		{
+			title:  "vars from the file",
+			script: "ci/unit.sh",
+		},
+		{
+			title:  "vars given to the converter take precedence",
+			vars:   atc.Source{"ci_dir": "release"},
+			script: "release/unit.sh",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			result, err := Convert(Options{
+				ProjectName:   "ci",
+				PipelineName:  "main",
+				Config:        readFixture(t, "with-vars.yml"),
+				TaskArtifacts: map[string]fs.FS{"repo": artifact},
+				Vars:          test.vars,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result.Warnings) != 0 {
+				t.Errorf("expected no warnings, got %v", result.Warnings)
+			}
+
+			script := generatedFile(t, result, "tasks/scripts/unit.sh")
+			expected, _ := fs.ReadFile(artifact, test.script)
+			if string(script) != string(expected) {
+				t.Errorf("expected the script from %s, got:\n\n%s", test.script, script)
+			}
+		})
+	}
+}
+
+func TestParsePipelineVarsDocument(t *testing.T) {
+	config, vars, err := parsePipeline(readFixture(t, "with-vars.yml"), "refuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(config.Jobs) != 1 || len(config.Resources) != 1 {
+		t.Errorf("expected the first document to be the pipeline, got %#v", config)
+	}
+
+	if len(vars) != 1 || vars["ci_dir"] != "ci" {
+		t.Errorf("expected the second document to be vars, got %#v", vars)
+	}
+
+	payload, err := marshalPipeline(config, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, remarshalled, err := parsePipeline(payload, "refuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(remarshalled) != 1 || remarshalled["ci_dir"] != "ci" {
+		t.Errorf("expected the vars to be marshalled with the pipeline, got %#v", remarshalled)
+	}
+}
+
+func TestMultiDoc(t *testing.T) {
+	payload := readFixture(t, "concatenated.yml")
+
+	t.Run("refused by default", func(t *testing.T) {
+		for _, multiDoc := range []string{"", "refuse"} {
+			_, err := Convert(Options{
+				ProjectName:   "ci",
+				PipelineName:  "main",
+				Config:        payload,
+				TaskArtifacts: map[string]fs.FS{"repo": ciArtifact},
+				MultiDoc:      multiDoc,
+			})
+
+			var invalid ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("expected a validation error with --multi-doc='%s', got %v", multiDoc, err)
+			}
+
+			if !strings.Contains(err.Error(), "document 2 has 'groups' like a pipeline") {
+				t.Errorf("expected the second document to be named, got %v", err)
+			}
+		}
+	})
+
+	t.Run("concatenated", func(t *testing.T) {
+		config, vars, err := parsePipeline(payload, "concat")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := map[string][]string{}
+		for _, group := range config.Groups {
+			names["groups"] = append(names["groups"], group.Name)
+		}
+
+		for _, res := range config.Resources {
+			names["resources"] = append(names["resources"], res.Name)
+		}
+
+		for _, res := range config.ResourceTypes {
+			names["resource types"] = append(names["resource types"], res.Name)
+		}
+
+		for _, job := range config.Jobs {
+			names["jobs"] = append(names["jobs"], job.Name)
+		}
+
+		expected := map[string][]string{
+			"groups":         {"test", "deploy"},
+			"resources":      {"repo", "notify"},
+			"resource types": {"slack"},
+			"jobs":           {"unit", "deploy"},
+		}
+
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("expected %v, got %v", expected, names)
+		}
+
+		// a trailing document of vars is still vars
+		if !reflect.DeepEqual(vars, atc.Source{"ci_dir": "ci"}) {
+			t.Errorf("expected the last document to be vars, got %#v", vars)
+		}
+	})
+
+	t.Run("converted", func(t *testing.T) {
+		result, err := Convert(Options{
+			ProjectName:   "ci",
+			PipelineName:  "main",
+			Config:        payload,
+			TaskArtifacts: map[string]fs.FS{"repo": ciArtifact},
+			MultiDoc:      "concat",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the task is found through the var in the last document
+		generatedFile(t, result, "tasks/unit.yml")
+
+		generatedFile(t, result, "resources/notify.yml")
+		generatedFile(t, result, "resource-types/slack.yml")
+
+		converted := convertedPipeline(t, result, "main")
+		if len(converted.Jobs) != 2 || len(converted.Groups) != 2 {
+			t.Errorf("expected both documents' jobs and groups, got %#v", converted)
+		}
+	})
+}
+
+func TestMultiDocDuplicateNames(t *testing.T) {
+	first := `
+groups:
+- name: all
+  jobs: [unit]
+resource_types:
+- name: slack
+  type: registry-image
+  source: {repository: example/slack-resource}
+resources:
+- name: repo
+  type: git
+  source: {uri: https://example.com/repo.git}
+jobs:
+- name: unit
+  plan:
+  - get: repo
+`
+
+	for _, test := range []struct {
+		title  string
+		second string
+		dupes  string
+	}{
+		{
+			title: "group",
+			second: `
+groups:
+- name: all
+  jobs: [deploy]
+jobs:
+- name: deploy
+  plan:
+  - get: repo
+`,
+			dupes: "group 'all'",
+		},
+		{
+			title: "resource",
+			second: `
+resources:
+- name: repo
+  type: git
+  source: {uri: https://example.com/other.git}
+`,
+			dupes: "resource 'repo'",
+		},
+		{
+			title: "resource type",
+			second: `
+resource_types:
+- name: slack
+  type: registry-image
+  source: {repository: example/other-slack-resource}
+`,
+			dupes: "resource type 'slack'",
+		},
+		{
+			title: "job",
+			second: `
+jobs:
+- name: unit
+  plan:
+  - get: repo
+`,
+			dupes: "job 'unit'",
+		},
+		{
+			title: "several",
+			second: `
+resources:
+- name: repo
+  type: git
+  source: {uri: https://example.com/other.git}
+jobs:
+- name: unit
+  plan:
+  - get: repo
+`,
+			dupes: "resource 'repo', job 'unit'",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			_, _, err := parsePipeline([]byte(first+"---"+test.second), "concat")
+
+			var invalid ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+
+			expected := "document 2 repeats names from earlier documents: " + test.dupes
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf("expected '%s', got '%s'", expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package pipe2proj
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError is returned when the pipeline config can't be converted as
+// given.
+type ValidationError struct {
+	Message string
+}
+
+func (err ValidationError) Error() string {
+	return err.Message
+}
+
+func invalidf(format string, args ...interface{}) error {
+	return ValidationError{Message: fmt.Sprintf(format, args...)}
+}
+
+// PipelineErrors is returned when some of several pipelines fail to convert
+// with KeepGoing set. It unwraps to the first failure.
+type PipelineErrors struct {
+	Errors []error
+	Total  int
+
+	// the most failures to describe, noting how many more there are; all of
+	// them if zero
+	Max int
+}
+
+func (err PipelineErrors) Error() string {
+	var msgs []string
+	for i, e := range err.Errors {
+		if err.Max > 0 && i == err.Max {
+			msgs = append(msgs, fmt.Sprintf("...and %d more", len(err.Errors)-err.Max))
+			break
+		}
+
+		msgs = append(msgs, e.Error())
+	}
+
+	return fmt.Sprintf("%d of %d pipelines failed:\n\n%s", len(err.Errors), err.Total, strings.Join(msgs, "\n\n"))
+}
+
+func (err PipelineErrors) Unwrap() error {
+	return err.Errors[0]
+}
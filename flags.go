@@ -0,0 +1,131 @@
+package pipe2proj
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ResourceRename is a flag value of the form 'old=new'.
+type ResourceRename struct {
+	Old string
+	New string
+}
+
+func (rename *ResourceRename) UnmarshalFlag(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("invalid resource rename '%s' (expected old=new)", value)
+	}
+
+	rename.Old = parts[0]
+	rename.New = parts[1]
+
+	return nil
+}
+
+func (rename ResourceRename) MarshalFlag() (string, error) {
+	return rename.Old + "=" + rename.New, nil
+}
+
+// SourceRewrite is a flag value of the form 'TYPE.KEY=REGEX=>REPLACEMENT',
+// where KEY may be a dot-separated path into nested source config.
+type SourceRewrite struct {
+	Type        string
+	Key         []string
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+func (rewrite *SourceRewrite) UnmarshalFlag(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid source rewrite '%s' (expected TYPE.KEY=REGEX=>REPLACEMENT)", value)
+	}
+
+	path := strings.Split(parts[0], ".")
+	if len(path) < 2 || path[0] == "" {
+		return fmt.Errorf("invalid source rewrite '%s' (expected TYPE.KEY=REGEX=>REPLACEMENT)", value)
+	}
+
+	exprs := strings.SplitN(parts[1], "=>", 2)
+	if len(exprs) != 2 || exprs[0] == "" {
+		return fmt.Errorf("invalid source rewrite '%s' (expected TYPE.KEY=REGEX=>REPLACEMENT)", value)
+	}
+
+	pattern, err := regexp.Compile(exprs[0])
+	if err != nil {
+		return fmt.Errorf("invalid source rewrite pattern '%s': %s", exprs[0], err)
+	}
+
+	rewrite.Type = path[0]
+	rewrite.Key = path[1:]
+	rewrite.Pattern = pattern
+	rewrite.Replacement = exprs[1]
+
+	return nil
+}
+
+func (rewrite SourceRewrite) MarshalFlag() (string, error) {
+	return fmt.Sprintf("%s.%s=%s=>%s", rewrite.Type, strings.Join(rewrite.Key, "."), rewrite.Pattern, rewrite.Replacement), nil
+}
+
+// SourceOverride is a flag value of the form 'RESOURCE.KEY=VALUE', where KEY
+// may be a dot-separated path into nested source config.
+type SourceOverride struct {
+	Resource string
+	Key      []string
+	Value    string
+}
+
+func (override *SourceOverride) UnmarshalFlag(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid source override '%s' (expected RESOURCE.KEY=VALUE)", value)
+	}
+
+	path := strings.Split(parts[0], ".")
+	if len(path) < 2 || path[0] == "" {
+		return fmt.Errorf("invalid source override '%s' (expected RESOURCE.KEY=VALUE)", value)
+	}
+
+	for _, key := range path[1:] {
+		if key == "" {
+			return fmt.Errorf("invalid source override '%s' (expected RESOURCE.KEY=VALUE)", value)
+		}
+	}
+
+	override.Resource = path[0]
+	override.Key = path[1:]
+	override.Value = parts[1]
+
+	return nil
+}
+
+func (override SourceOverride) MarshalFlag() (string, error) {
+	return fmt.Sprintf("%s.%s=%s", override.Resource, strings.Join(override.Key, "."), override.Value), nil
+}
+
+// TemplateData is a flag value of a JSON object to give to templates.
+type TemplateData struct {
+	Value map[string]interface{}
+}
+
+func (data *TemplateData) UnmarshalFlag(value string) error {
+	err := json.Unmarshal([]byte(value), &data.Value)
+	if err != nil {
+		return fmt.Errorf("invalid template data '%s' (expected a JSON object): %s", value, err)
+	}
+
+	return nil
+}
+
+func (data TemplateData) MarshalFlag() (string, error) {
+	payload, err := json.Marshal(data.Value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(payload), nil
+}
@@ -1,14 +1,16 @@
 module github.com/vito/pipe2proj
 
-go 1.12
+go 1.16
 
 require (
 	github.com/concourse/concourse v0.0.0-20190703134914-5b0160e515a3
-	github.com/concourse/flag v1.0.0
-	github.com/gobuffalo/packd v0.3.0
-	github.com/gobuffalo/packr/v2 v2.5.2
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/jessevdk/go-flags v1.4.0
+	github.com/konsorten/go-windows-terminal-sequences v1.0.2 // indirect
+	github.com/mitchellh/mapstructure v1.1.2 // indirect
 	github.com/sergi/go-diff v1.0.0
 	github.com/sirupsen/logrus v1.4.2
-	gopkg.in/yaml.v2 v2.2.2
+	go.yaml.in/yaml/v3 v3.0.5
+	golang.org/x/crypto v0.0.0-20190621222207-cc06ce4a13d4 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,284 @@
+package pipe2proj
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/concourse/concourse/atc"
+)
+
+// Graph is the jobs and resources of the converted pipelines, with an edge
+// for each resource a job gets or puts and each job it has passed.
+type Graph struct {
+	Pipelines []PipelineGraph
+}
+
+// PipelineGraph is the graph of a single pipeline.
+type PipelineGraph struct {
+	Name string
+
+	Jobs      []string
+	Resources []string
+	Groups    []GraphGroup
+	Edges     []GraphEdge
+	Passed    []PassedEdge
+}
+
+// GraphGroup is a group of the pipeline's jobs.
+type GraphGroup struct {
+	Name string
+	Jobs []string
+}
+
+// GraphEdge is a job getting or putting a resource.
+type GraphEdge struct {
+	Job      string
+	Resource string
+
+	// whether the job puts the resource rather than getting it
+	Put bool
+}
+
+// PassedEdge is a job only running with versions which passed through an
+// upstream job.
+type PassedEdge struct {
+	Upstream string
+	Job      string
+}
+
+// pipelineGraph collects the resources each job gets and puts and the jobs
+// it has passed, with each edge only included once per job.
+func pipelineGraph(name string, config PipelineConfig) (PipelineGraph, error) {
+	graph := PipelineGraph{
+		Name: name,
+	}
+
+	for _, res := range config.Resources {
+		graph.Resources = append(graph.Resources, res.Name)
+	}
+
+	for _, group := range config.Groups {
+		graph.Groups = append(graph.Groups, GraphGroup{
+			Name: group.Name,
+			Jobs: group.Jobs,
+		})
+	}
+
+	for _, job := range config.Jobs {
+		graph.Jobs = append(graph.Jobs, job.Name)
+
+		seen := map[GraphEdge]bool{}
+		seenPassed := map[PassedEdge]bool{}
+		err := VisitJob(job, func(_ StepPath, p atc.PlanConfig) error {
+			for _, upstream := range p.Passed {
+				edge := PassedEdge{
+					Upstream: upstream,
+					Job:      job.Name,
+				}
+
+				if !seenPassed[edge] {
+					seenPassed[edge] = true
+					graph.Passed = append(graph.Passed, edge)
+				}
+			}
+
+			if p.Get == "" && p.Put == "" {
+				return nil
+			}
+
+			edge := GraphEdge{
+				Job:      job.Name,
+				Resource: p.ResourceName(),
+				Put:      p.Put != "",
+			}
+
+			if !seen[edge] {
+				seen[edge] = true
+				graph.Edges = append(graph.Edges, edge)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return PipelineGraph{}, fmt.Errorf("job '%s': %w", job.Name, err)
+		}
+	}
+
+	return graph, nil
+}
+
+// WriteDOT writes the graph in Graphviz's DOT language, with each pipeline in
+// its own cluster and each group's jobs in a cluster within it. Edges point
+// from resources to the jobs which get them, from jobs to the resources they
+// put, and from jobs to the jobs which have them passed.
+func (graph Graph) WriteDOT(w io.Writer) error {
+	var out strings.Builder
+	fmt.Fprintln(&out, "digraph pipelines {")
+	fmt.Fprintln(&out, "  rankdir=LR;")
+
+	for _, pipeline := range graph.Pipelines {
+		fmt.Fprintln(&out)
+		fmt.Fprintf(&out, "  subgraph %s {\n", strconv.Quote("cluster_"+pipeline.Name))
+		fmt.Fprintf(&out, "    label=%s;\n", strconv.Quote(pipeline.Name))
+
+		groupJobs, ungrouped := pipeline.groupedJobs()
+		for i, group := range pipeline.Groups {
+			if len(groupJobs[i]) == 0 {
+				continue
+			}
+
+			fmt.Fprintf(&out, "    subgraph %s {\n", strconv.Quote("cluster_"+pipeline.Name+"/group/"+group.Name))
+			fmt.Fprintf(&out, "      label=%s;\n", strconv.Quote(group.Name))
+
+			for _, job := range groupJobs[i] {
+				fmt.Fprintf(&out, "      %s [label=%s, shape=box];\n", dotID(pipeline.Name, "job", job), strconv.Quote(job))
+			}
+
+			fmt.Fprintln(&out, "    }")
+		}
+
+		for _, job := range ungrouped {
+			fmt.Fprintf(&out, "    %s [label=%s, shape=box];\n", dotID(pipeline.Name, "job", job), strconv.Quote(job))
+		}
+
+		for _, res := range pipeline.Resources {
+			fmt.Fprintf(&out, "    %s [label=%s, shape=ellipse];\n", dotID(pipeline.Name, "resource", res), strconv.Quote(res))
+		}
+
+		for _, edge := range pipeline.Edges {
+			job := dotID(pipeline.Name, "job", edge.Job)
+			res := dotID(pipeline.Name, "resource", edge.Resource)
+
+			if edge.Put {
+				fmt.Fprintf(&out, "    %s -> %s [style=dashed];\n", job, res)
+			} else {
+				fmt.Fprintf(&out, "    %s -> %s;\n", res, job)
+			}
+		}
+
+		for _, edge := range pipeline.Passed {
+			fmt.Fprintf(&out, "    %s -> %s [style=dotted];\n", dotID(pipeline.Name, "job", edge.Upstream), dotID(pipeline.Name, "job", edge.Job))
+		}
+
+		fmt.Fprintln(&out, "  }")
+	}
+
+	fmt.Fprintln(&out, "}")
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// groupedJobs returns the jobs in each group, in the order of the pipeline's
+// jobs, and the jobs which aren't in any. A node can only be drawn in one
+// group, so jobs in several groups are drawn in the first.
+func (pipeline PipelineGraph) groupedJobs() ([][]string, []string) {
+	jobGroups := map[string]int{}
+	for i, group := range pipeline.Groups {
+		for _, job := range group.Jobs {
+			if _, found := jobGroups[job]; !found {
+				jobGroups[job] = i
+			}
+		}
+	}
+
+	groupJobs := make([][]string, len(pipeline.Groups))
+
+	var ungrouped []string
+	for _, job := range pipeline.Jobs {
+		i, found := jobGroups[job]
+		if !found {
+			ungrouped = append(ungrouped, job)
+			continue
+		}
+
+		groupJobs[i] = append(groupJobs[i], job)
+	}
+
+	return groupJobs, ungrouped
+}
+
+func dotID(pipeline string, kind string, name string) string {
+	return strconv.Quote(pipeline + "/" + kind + "/" + name)
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart, with each pipeline and
+// group in its own subgraph. Edges point the same way as with WriteDOT.
+func (graph Graph) WriteMermaid(w io.Writer) error {
+	var out strings.Builder
+	fmt.Fprintln(&out, "flowchart LR")
+
+	for i, pipeline := range graph.Pipelines {
+		// Mermaid IDs can't contain most punctuation, so nodes are numbered
+		// and named with labels
+		ids := map[string]string{}
+		for j, job := range pipeline.Jobs {
+			ids["job/"+job] = fmt.Sprintf("p%d_j%d", i, j)
+		}
+
+		for j, res := range pipeline.Resources {
+			ids["resource/"+res] = fmt.Sprintf("p%d_r%d", i, j)
+		}
+
+		fmt.Fprintf(&out, "  subgraph p%d[%s]\n", i, mermaidLabel(pipeline.Name))
+
+		groupJobs, ungrouped := pipeline.groupedJobs()
+		for j, group := range pipeline.Groups {
+			if len(groupJobs[j]) == 0 {
+				continue
+			}
+
+			fmt.Fprintf(&out, "    subgraph p%d_g%d[%s]\n", i, j, mermaidLabel(group.Name))
+
+			for _, job := range groupJobs[j] {
+				fmt.Fprintf(&out, "      %s[%s]\n", ids["job/"+job], mermaidLabel(job))
+			}
+
+			fmt.Fprintln(&out, "    end")
+		}
+
+		for _, job := range ungrouped {
+			fmt.Fprintf(&out, "    %s[%s]\n", ids["job/"+job], mermaidLabel(job))
+		}
+
+		for _, res := range pipeline.Resources {
+			fmt.Fprintf(&out, "    %s([%s])\n", ids["resource/"+res], mermaidLabel(res))
+		}
+
+		for _, edge := range pipeline.Edges {
+			res, found := ids["resource/"+edge.Resource]
+			if !found {
+				res = fmt.Sprintf("p%d_r%d", i, len(ids))
+				ids["resource/"+edge.Resource] = res
+				fmt.Fprintf(&out, "    %s([%s])\n", res, mermaidLabel(edge.Resource))
+			}
+
+			if edge.Put {
+				fmt.Fprintf(&out, "    %s -.-> %s\n", ids["job/"+edge.Job], res)
+			} else {
+				fmt.Fprintf(&out, "    %s --> %s\n", res, ids["job/"+edge.Job])
+			}
+		}
+
+		for _, edge := range pipeline.Passed {
+			upstream, found := ids["job/"+edge.Upstream]
+			if !found {
+				continue
+			}
+
+			fmt.Fprintf(&out, "    %s ==> %s\n", upstream, ids["job/"+edge.Job])
+		}
+
+		fmt.Fprintln(&out, "  end")
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// mermaidLabel quotes a label, escaping any quotes within it.
+func mermaidLabel(label string) string {
+	return `"` + strings.ReplaceAll(label, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,52 @@
+package pipe2proj
+
+import (
+	"bytes"
+	"io/fs"
+	"path/filepath"
+	"testing"
+)
+
+func TestGraph(t *testing.T) {
+	result, err := Convert(Options{
+		ProjectName:   "ci",
+		PipelineName:  "main",
+		Config:        readFixture(t, filepath.Join("graph", "pipeline.yml")),
+		TaskArtifacts: map[string]fs.FS{"repo": ciArtifact},
+
+		// the graph is of the converted jobs, so the audit job gets image
+		// rather than repo
+		StepFilter: `if [ "$P2P_JOB" = audit ]; then echo "get: image"; fi`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []struct {
+		golden string
+		write  func(*bytes.Buffer) error
+	}{
+		{
+			golden: "pipeline.dot",
+			write: func(buf *bytes.Buffer) error {
+				return result.Graph.WriteDOT(buf)
+			},
+		},
+		{
+			golden: "pipeline.mmd",
+			write: func(buf *bytes.Buffer) error {
+				return result.Graph.WriteMermaid(buf)
+			},
+		},
+	} {
+		t.Run(format.golden, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := format.write(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			checkGolden(t, filepath.Join("testdata", "graph", format.golden), buf.Bytes())
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package pipe2proj
+
+import (
+	"github.com/concourse/concourse/atc"
+	"github.com/sirupsen/logrus"
+)
+
+// excludeJobs removes the named jobs from the pipeline. A job can't be
+// excluded while another job has it in a passed constraint.
+func excludeJobs(config *PipelineConfig, names []string) error {
+	excluded := map[string]bool{}
+	for _, name := range names {
+		if _, found := config.Jobs.Lookup(name); !found {
+			return invalidf("cannot exclude unknown job '%s'", name)
+		}
+
+		excluded[name] = true
+	}
+
+	if len(excluded) == 0 {
+		return nil
+	}
+
+	var jobs atc.JobConfigs
+	for _, job := range config.Jobs {
+		if excluded[job.Name] {
+			logrus.WithFields(logrus.Fields{
+				"name": job.Name,
+			}).Info("excluding job")
+
+			continue
+		}
+
+		err := VisitJob(job, func(path StepPath, p atc.PlanConfig) error {
+			for _, passed := range p.Passed {
+				if excluded[passed] {
+					return invalidf("cannot exclude job '%s': job '%s' has it in a passed constraint at %s", passed, job.Name, path)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	config.Jobs = jobs
+
+	return nil
+}
+
+// reconcileGroups removes the excluded jobs from each group, returning a
+// warning for each group left without any jobs. Any other job or resource a
+// group names which isn't in the pipeline is left for validateGroups to
+// report, as it was already stale.
+func reconcileGroups(config *PipelineConfig, excluded []string) []Warning {
+	var warnings []Warning
+	for i, group := range config.Groups {
+		var jobs []string
+		for _, name := range group.Jobs {
+			if containsString(excluded, name) {
+				logrus.WithFields(logrus.Fields{
+					"group": group.Name,
+					"job":   name,
+				}).Info("removing job from group")
+
+				continue
+			}
+
+			jobs = append(jobs, name)
+		}
+
+		if len(jobs) == 0 && len(group.Jobs) > 0 {
+			warnings = append(warnings, Warning{
+				Fields: logrus.Fields{
+					"group": group.Name,
+				},
+				Message: "group no longer has any jobs",
+			})
+		}
+
+		config.Groups[i].Jobs = jobs
+	}
+
+	return warnings
+}
@@ -0,0 +1,88 @@
+package pipe2proj
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/concourse/concourse/atc"
+	"github.com/sirupsen/logrus"
+)
+
+func TestExcludeJobFromGroups(t *testing.T) {
+	result, err := Convert(Options{
+		ProjectName:  "ci",
+		PipelineName: "main",
+		Config:       readFixture(t, "exclude-job.yml"),
+		ExcludeJobs:  []string{"deploy"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	converted := convertedPipeline(t, result, "main")
+
+	var jobs []string
+	for _, job := range converted.Jobs {
+		jobs = append(jobs, job.Name)
+	}
+
+	if !reflect.DeepEqual(jobs, []string{"unit", "integration"}) {
+		t.Errorf("expected jobs [unit integration], got %v", jobs)
+	}
+
+	// only the excluded job is removed; resources are still in the pipeline,
+	// so the groups keep them
+	expectedGroups := atc.GroupConfigs{
+		{Name: "all", Jobs: []string{"unit", "integration"}, Resources: []string{"repo", "env"}},
+		{Name: "test", Jobs: []string{"unit", "integration"}},
+		{Name: "deploy", Resources: []string{"env"}},
+	}
+
+	if !reflect.DeepEqual(converted.Groups, expectedGroups) {
+		t.Errorf("expected groups %#v, got %#v", expectedGroups, converted.Groups)
+	}
+
+	expectedWarnings := []Warning{{
+		Fields:  logrus.Fields{"group": "deploy"},
+		Message: "group no longer has any jobs",
+	}}
+
+	if !reflect.DeepEqual(result.Warnings, expectedWarnings) {
+		t.Errorf("expected warnings %v, got %v", expectedWarnings, result.Warnings)
+	}
+}
+
+func TestExcludeJobErrors(t *testing.T) {
+	for _, test := range []struct {
+		title   string
+		exclude []string
+		message string
+	}{
+		{
+			title:   "unknown job",
+			exclude: []string{"bogus"},
+			message: "cannot exclude unknown job 'bogus'",
+		},
+		{
+			title:   "job in a passed constraint",
+			exclude: []string{"integration"},
+			message: "cannot exclude job 'integration': job 'deploy' has it in a passed constraint at plan[0]",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			_, err := Convert(Options{
+				ProjectName:  "ci",
+				PipelineName: "main",
+				Config:       readFixture(t, "exclude-job.yml"),
+				ExcludeJobs:  test.exclude,
+			})
+
+			var invalid ValidationError
+			if !errors.As(err, &invalid) || !strings.Contains(err.Error(), test.message) {
+				t.Fatalf("expected a validation error with '%s', got %v", test.message, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package pipe2proj
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// defaultIcons are the icons given to resources of each type by AutoIcons,
+// named as in Material Design Icons, which Concourse uses.
+var defaultIcons = map[string]string{
+	"bosh-io-release":    "package-variant",
+	"bosh-io-stemcell":   "package-variant-closed",
+	"cf":                 "cloud-upload",
+	"concourse-pipeline": "pipe",
+	"docker-image":       "docker",
+	"git":                "git",
+	"github-release":     "github",
+	"hg":                 "mercurial",
+	"pool":               "pool",
+	"registry-image":     "docker",
+	"s3":                 "amazon",
+	"semver":             "tag",
+	"slack-notification": "slack",
+	"time":               "clock-outline",
+}
+
+// assignIcons gives each resource without an icon the icon for its type, if
+// there is one. Icons given for a type take precedence over the defaults.
+func assignIcons(config *PipelineConfig, icons map[string]string) {
+	for i, res := range config.Resources {
+		if res.Icon != "" {
+			continue
+		}
+
+		icon, found := icons[res.Type]
+		if !found {
+			icon, found = defaultIcons[res.Type]
+		}
+
+		if !found {
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"resource": res.Name,
+			"icon":     icon,
+		}).Info("assigning icon")
+
+		config.Resources[i].Icon = icon
+	}
+}
@@ -0,0 +1,143 @@
+package pipe2proj
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path"
+	"regexp"
+
+	"github.com/concourse/concourse/atc"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+var unsafeNameRegexp = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// extractedImage is an image resource shared by one or more converted tasks.
+type extractedImage struct {
+	Name  string
+	Image atc.ImageResource
+	Tasks []string
+
+	// indexes of the tasks in those given to extractImages
+	taskIndexes []int
+}
+
+// extractImages groups the tasks' image resources by content, in the order
+// they're first used. Each image is named after its repository and tag, with
+// a numeric suffix for images which would otherwise share a name.
+func extractImages(tasks []convertedTask) ([]extractedImage, error) {
+	var images []extractedImage
+	byHash := map[string]int{}
+	names := map[string]bool{}
+
+	for i, task := range tasks {
+		image := task.Config.ImageResource
+		if image == nil {
+			continue
+		}
+
+		payload, err := yaml.Marshal(image)
+		if err != nil {
+			return nil, err
+		}
+
+		hash := fmt.Sprintf("%x", sha256.Sum256(payload))
+
+		if existing, found := byHash[hash]; found {
+			images[existing].Tasks = append(images[existing].Tasks, task.Name)
+			images[existing].taskIndexes = append(images[existing].taskIndexes, i)
+			continue
+		}
+
+		base := imageName(*image)
+		name := base
+		for n := 2; names[name]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+
+		names[name] = true
+		byHash[hash] = len(images)
+
+		images = append(images, extractedImage{
+			Name:        name,
+			Image:       *image,
+			Tasks:       []string{task.Name},
+			taskIndexes: []int{i},
+		})
+	}
+
+	return images, nil
+}
+
+// imageName derives a file name from the image's repository and tag, falling
+// back on its type.
+func imageName(image atc.ImageResource) string {
+	name := image.Type
+
+	if repository, ok := image.Source["repository"].(string); ok && repository != "" {
+		name = path.Base(repository)
+
+		if tag, found := image.Source["tag"]; found {
+			name += "-" + fmt.Sprint(tag)
+		}
+	}
+
+	return unsafeNameRegexp.ReplaceAllString(name, "-")
+}
+
+// source keys of image resources which hold registry credentials
+var registryCredentialKeys = []string{"username", "password", "token"}
+
+// externalizeRegistryCredentials replaces literal registry credentials in
+// each image's source with ((<image>-<key>)) vars, in the image and in the
+// tasks which use it, returning the vars with their original values in the
+// order of the images. Credentials which already use a var are left alone.
+func externalizeRegistryCredentials(images []extractedImage, tasks []convertedTask) yaml.MapSlice {
+	var vars yaml.MapSlice
+	for i, image := range images {
+		source := atc.Source{}
+		for key, val := range image.Image.Source {
+			source[key] = val
+		}
+
+		externalized := false
+		for _, key := range registryCredentialKeys {
+			val, ok := source[key].(string)
+			if !ok || val == "" || varRegexp.MatchString(val) {
+				continue
+			}
+
+			name := image.Name + "-" + key
+
+			logrus.WithFields(logrus.Fields{
+				"image": image.Name,
+				"var":   name,
+			}).Info("externalizing registry credential")
+
+			vars = append(vars, yaml.MapItem{
+				Key:   name,
+				Value: val,
+			})
+
+			source[key] = "((" + name + "))"
+			externalized = true
+		}
+
+		if !externalized {
+			continue
+		}
+
+		images[i].Image.Source = source
+
+		// the tasks' image resources are copied rather than changed, as
+		// their sources may be shared with the tasks' original configs
+		for _, t := range image.taskIndexes {
+			imageResource := *tasks[t].Config.ImageResource
+			imageResource.Source = source
+			tasks[t].Config.ImageResource = &imageResource
+		}
+	}
+
+	return vars
+}
@@ -0,0 +1,254 @@
+package pipe2proj
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// imagesArtifact has two tasks sharing an image and one with its own.
+var imagesArtifact = fstest.MapFS{
+	"ci/unit.yml": {Data: []byte(`platform: linux
+image_resource:
+  type: registry-image
+  source: {repository: golang, tag: "1.21"}
+run: {path: go}
+`)},
+	"ci/lint.yml": {Data: []byte(`platform: linux
+image_resource:
+  type: registry-image
+  source: {repository: golang, tag: "1.21"}
+run: {path: go}
+`)},
+	"ci/deploy.yml": {Data: []byte(`platform: linux
+image_resource:
+  type: registry-image
+  source: {repository: example/deployer}
+run: {path: deploy}
+`)},
+	"ci/local.yml": {Data: []byte(`platform: linux
+rootfs_uri: docker:///alpine
+run: {path: "true"}
+`)},
+}
+
+const imagesPipeline = `
+jobs:
+- name: test
+  plan:
+  - task: unit
+    file: repo/ci/unit.yml
+  - task: lint
+    file: repo/ci/lint.yml
+  - task: deploy
+    file: repo/ci/deploy.yml
+  - task: local
+    file: repo/ci/local.yml
+`
+
+func TestExtractImages(t *testing.T) {
+	result, err := Convert(Options{
+		ProjectName:   "ci",
+		PipelineName:  "main",
+		Config:        []byte(imagesPipeline),
+		TaskArtifacts: map[string]fs.FS{"repo": imagesArtifact},
+		ExtractImages: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"images/golang-1.21.yml", "images/deployer.yml"} {
+		generatedFile(t, result, path)
+	}
+
+	for task, image := range map[string]string{
+		"unit":   "images/golang-1.21.yml",
+		"lint":   "images/golang-1.21.yml",
+		"deploy": "images/deployer.yml",
+	} {
+		payload := string(generatedFile(t, result, "tasks/"+task+".yml"))
+
+		expected := "---\n# image: " + image + "\nplatform: linux\n"
+		if !strings.HasPrefix(payload, expected) {
+			t.Errorf("task %s: expected it to start with:\n\n%s\n\ngot:\n\n%s", task, expected, payload)
+		}
+
+		// the task still runs as-is
+		if !strings.Contains(payload, "image_resource:") {
+			t.Errorf("task %s: expected its image_resource to be kept:\n\n%s", task, payload)
+		}
+	}
+
+	local := string(generatedFile(t, result, "tasks/local.yml"))
+	if strings.Contains(local, "# image:") {
+		t.Errorf("expected no image reference in a task without an image_resource:\n\n%s", local)
+	}
+}
+
+func TestExtractImagesReferenceLineEndings(t *testing.T) {
+	result, err := Convert(Options{
+		ProjectName:   "ci",
+		PipelineName:  "main",
+		Config:        []byte(imagesPipeline),
+		TaskArtifacts: map[string]fs.FS{"repo": imagesArtifact},
+		ExtractImages: true,
+		NoTemplates:   true,
+		LineEndings:   "crlf",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without templates there's no document marker to put it after
+	payload := string(generatedFile(t, result, "tasks/unit.yml"))
+	if !strings.HasPrefix(payload, "# image: images/golang-1.21.yml\r\n") {
+		t.Errorf("expected the reference to start the file, with its line ending:\n\n%q", payload)
+	}
+}
+
+func TestExtractImagesReferenceCustomTemplate(t *testing.T) {
+	templates, err := NewTemplates(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = templates.AddDir(fstest.MapFS{
+		"task.tmpl": {Data: []byte("{{. | yaml 0}}\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		title    string
+		marker   bool
+		expected string
+	}{
+		{
+			title:    "without a document marker",
+			expected: "# image: images/golang-1.21.yml\nplatform: linux\n",
+		},
+		{
+			title:    "with a document marker",
+			marker:   true,
+			expected: "---\n# image: images/golang-1.21.yml\nplatform: linux\n",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			result, err := Convert(Options{
+				ProjectName:        "ci",
+				PipelineName:       "main",
+				Config:             []byte(imagesPipeline),
+				TaskArtifacts:      map[string]fs.FS{"repo": imagesArtifact},
+				ExtractImages:      true,
+				Templates:          templates,
+				YAMLDocumentMarker: test.marker,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			payload := string(generatedFile(t, result, "tasks/unit.yml"))
+			if !strings.HasPrefix(payload, test.expected) {
+				t.Errorf("expected it to start with:\n\n%s\n\ngot:\n\n%s", test.expected, payload)
+			}
+		})
+	}
+}
+
+func TestExternalizeRegistryCredentials(t *testing.T) {
+	const password = "hunter2-literal-password"
+
+	privateTask := `platform: linux
+image_resource:
+  type: registry-image
+  source:
+    repository: registry.example.com/private
+    username: ci-bot
+    password: ` + password + `
+run: {path: make}
+`
+
+	artifact := fstest.MapFS{
+		"ci/build.yml": {Data: []byte(privateTask)},
+		"ci/test.yml":  {Data: []byte(privateTask)},
+		"ci/push.yml": {Data: []byte(`platform: linux
+image_resource:
+  type: registry-image
+  source:
+    repository: registry.example.com/pusher
+    username: ci-bot
+    password: ((registry-password))
+run: {path: push}
+`)},
+	}
+
+	result, err := Convert(Options{
+		ProjectName:  "ci",
+		PipelineName: "main",
+		Config: []byte(`
+jobs:
+- name: build
+  plan:
+  - task: build
+    file: repo/ci/build.yml
+  - task: test
+    file: repo/ci/test.yml
+  - task: push
+    file: repo/ci/push.yml
+`),
+		TaskArtifacts:                  map[string]fs.FS{"repo": artifact},
+		ExtractImages:                  true,
+		ExternalizeRegistryCredentials: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range result.Files {
+		if file.Path == registryCredentialsPath {
+			continue
+		}
+
+		if strings.Contains(string(file.Payload), password) {
+			t.Errorf("%s: expected the password to be externalized:\n\n%s", file.Path, file.Payload)
+		}
+	}
+
+	for _, path := range []string{"images/private.yml", "tasks/build.yml", "tasks/test.yml"} {
+		payload := string(generatedFile(t, result, path))
+
+		for _, ref := range []string{"password: ((private-password))", "username: ((private-username))"} {
+			if !strings.Contains(payload, ref) {
+				t.Errorf("%s: expected it to have %s:\n\n%s", path, ref, payload)
+			}
+		}
+	}
+
+	// credentials already using vars are left alone
+	for _, path := range []string{"images/pusher.yml", "tasks/push.yml"} {
+		payload := string(generatedFile(t, result, path))
+		if !strings.Contains(payload, "password: ((registry-password))") || !strings.Contains(payload, "username: ((pusher-username))") {
+			t.Errorf("%s: expected only the literal username to be externalized:\n\n%s", path, payload)
+		}
+	}
+
+	vars := string(generatedFile(t, result, registryCredentialsPath))
+
+	expected := `private-username: ci-bot
+private-password: ` + password + `
+pusher-username: ci-bot
+`
+
+	if vars != expected {
+		t.Errorf("expected %s to have:\n\n%s\n\ngot:\n\n%s", registryCredentialsPath, expected, vars)
+	}
+
+	for _, file := range result.Files {
+		if file.Path == registryCredentialsPath && file.Mode != 0600 {
+			t.Errorf("expected %s to only be readable by its owner, got %s", file.Path, file.Mode)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package pipe2proj
+
+import (
+	"sort"
+
+	"github.com/concourse/concourse/atc"
+	"github.com/sirupsen/logrus"
+)
+
+// artifactVisitFunc is called with a step along with the names of the
+// artifacts available to it, i.e. those fetched or produced by the steps
+// which run before it.
+type artifactVisitFunc func(path StepPath, step atc.PlanConfig, available map[string]bool)
+
+// artifactVisitor visits the steps of a job in the order they would run,
+// tracking the artifacts available to each one.
+type artifactVisitor struct {
+	// configs of tasks loaded from files, by their step's path
+	configs map[StepPath]atc.TaskConfig
+
+	fn artifactVisitFunc
+}
+
+// visitJob visits every step in the job's plan and hooks. Steps within
+// in_parallel or aggregate only see what was available before them, and
+// hooks see the artifacts of the step they're attached to.
+func (visitor artifactVisitor) visitJob(job atc.JobConfig) {
+	available := visitor.visitSteps("plan", job.Plan, map[string]bool{})
+
+	for i, hook := range jobHooks(job) {
+		if hook != nil {
+			visitor.visit(StepPath(hookKeys[i]), *hook, available)
+		}
+	}
+}
+
+// taskConfig returns the config of the task step, if it's known.
+func (visitor artifactVisitor) taskConfig(path StepPath, step atc.PlanConfig) (atc.TaskConfig, bool) {
+	if config, found := visitor.configs[path]; found {
+		return config, true
+	}
+
+	if step.TaskConfig != nil {
+		return *step.TaskConfig, true
+	}
+
+	return atc.TaskConfig{}, false
+}
+
+// visit visits the step and returns the artifacts available after it runs.
+func (visitor artifactVisitor) visit(path StepPath, plan atc.PlanConfig, available map[string]bool) map[string]bool {
+	visitor.fn(path, plan, available)
+
+	after := copyArtifacts(available)
+
+	switch {
+	case plan.Try != nil:
+		after = visitor.visit(path.field("try"), *plan.Try, available)
+
+	case plan.Do != nil:
+		after = visitor.visitSteps(path.field("do"), *plan.Do, available)
+
+	case plan.Aggregate != nil:
+		after = visitor.visitParallel(path.field("aggregate"), *plan.Aggregate, available)
+
+	case plan.InParallel != nil:
+		after = visitor.visitParallel(path.field("in_parallel"), plan.InParallel.Steps, available)
+
+	case plan.Get != "", plan.Put != "":
+		// puts fetch the version they create, too
+		after[plan.Name()] = true
+
+	case plan.Task != "":
+		config, _ := visitor.taskConfig(path, plan)
+
+		for _, output := range config.Outputs {
+			name := output.Name
+			if mapped, found := plan.OutputMapping[name]; found {
+				name = mapped
+			}
+
+			after[name] = true
+		}
+	}
+
+	for i, hook := range planHooks(plan) {
+		if hook != nil {
+			visitor.visit(path.field(hookKeys[i]), *hook, after)
+		}
+	}
+
+	return after
+}
+
+func (visitor artifactVisitor) visitSteps(path StepPath, steps atc.PlanSequence, available map[string]bool) map[string]bool {
+	for i, step := range steps {
+		available = visitor.visit(path.index(i), step, available)
+	}
+
+	return available
+}
+
+func (visitor artifactVisitor) visitParallel(path StepPath, steps atc.PlanSequence, available map[string]bool) map[string]bool {
+	after := copyArtifacts(available)
+	for i, step := range steps {
+		for name := range visitor.visit(path.index(i), step, available) {
+			after[name] = true
+		}
+	}
+
+	return after
+}
+
+func copyArtifacts(artifacts map[string]bool) map[string]bool {
+	copied := make(map[string]bool, len(artifacts))
+	for name := range artifacts {
+		copied[name] = true
+	}
+
+	return copied
+}
+
+// missingTaskInputs returns a warning for each required input of the job's
+// tasks which no step before the task provides. Tasks whose config isn't
+// known, e.g. because it wasn't converted, are skipped.
+func missingTaskInputs(job atc.JobConfig, configs map[StepPath]atc.TaskConfig) []Warning {
+	var warnings []Warning
+
+	visitor := artifactVisitor{
+		configs: configs,
+	}
+
+	visitor.fn = func(path StepPath, step atc.PlanConfig, available map[string]bool) {
+		if step.Task == "" {
+			return
+		}
+
+		config, found := visitor.taskConfig(path, step)
+		if !found {
+			return
+		}
+
+		var missing []string
+		for _, input := range config.Inputs {
+			if input.Optional {
+				continue
+			}
+
+			name := input.Name
+			if mapped, found := step.InputMapping[name]; found {
+				name = mapped
+			}
+
+			if !available[name] {
+				missing = append(missing, name)
+			}
+		}
+
+		sort.Strings(missing)
+
+		for _, name := range missing {
+			warnings = append(warnings, Warning{
+				Fields: logrus.Fields{
+					"job":   job.Name,
+					"task":  step.Task,
+					"step":  string(path),
+					"input": name,
+				},
+				Message: "task input is not provided by any step before it",
+			})
+		}
+	}
+
+	visitor.visitJob(job)
+
+	return warnings
+}
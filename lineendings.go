@@ -0,0 +1,29 @@
+package pipe2proj
+
+import (
+	"bytes"
+)
+
+// the byte order mark some editors, e.g. on Windows, start UTF-8 files with
+var byteOrderMark = []byte("\xef\xbb\xbf")
+
+// stripBOM removes the byte order mark from the start of the payload, if it
+// has one, so that it isn't copied into the project, e.g. before a script's
+// shebang.
+func stripBOM(payload []byte) []byte {
+	return bytes.TrimPrefix(payload, byteOrderMark)
+}
+
+// convertLineEndings ends every line of the payload with '\n' for "lf" or
+// '\r\n' for "crlf", leaving it as it is otherwise.
+func convertLineEndings(payload []byte, endings string) []byte {
+	switch endings {
+	case "lf":
+		return bytes.ReplaceAll(payload, []byte("\r\n"), []byte("\n"))
+	case "crlf":
+		lf := bytes.ReplaceAll(payload, []byte("\r\n"), []byte("\n"))
+		return bytes.ReplaceAll(lf, []byte("\n"), []byte("\r\n"))
+	default:
+		return payload
+	}
+}
@@ -0,0 +1,120 @@
+package pipe2proj
+
+import (
+	"github.com/concourse/concourse/atc"
+	"github.com/sirupsen/logrus"
+)
+
+// pipelineLints are run once over the parsed pipeline config.
+var pipelineLints = []func(PipelineConfig) []Warning{
+	lintResourceIcons,
+	lintSerialGroups,
+}
+
+// stepLints are run over every step in every job's plan. Each returns a
+// message if the step is problematic, or an empty string otherwise.
+var stepLints = []func(atc.PlanConfig) string{
+	lintAggregate,
+	lintPutVersionEvery,
+	lintStepTags,
+}
+
+// taskLints are run over every task config, both inline and converted.
+var taskLints = []func(atc.TaskConfig) string{
+	lintTaskPlatform,
+}
+
+func lintPipeline(config PipelineConfig) []Warning {
+	var warnings []Warning
+	for _, lint := range pipelineLints {
+		warnings = append(warnings, lint(config)...)
+	}
+
+	return warnings
+}
+
+func lintStep(fields logrus.Fields, step atc.PlanConfig) []Warning {
+	var warnings []Warning
+	for _, lint := range stepLints {
+		if msg := lint(step); msg != "" {
+			warnings = append(warnings, Warning{Fields: fields, Message: msg})
+		}
+	}
+
+	if step.TaskConfig != nil {
+		warnings = append(warnings, lintTask(fields, *step.TaskConfig)...)
+	}
+
+	return warnings
+}
+
+func lintTask(fields logrus.Fields, config atc.TaskConfig) []Warning {
+	var warnings []Warning
+	for _, lint := range taskLints {
+		if msg := lint(config); msg != "" {
+			warnings = append(warnings, Warning{Fields: fields, Message: msg})
+		}
+	}
+
+	return warnings
+}
+
+func lintResourceIcons(config PipelineConfig) []Warning {
+	var warnings []Warning
+	for _, res := range config.Resources {
+		if res.Icon == "" {
+			warnings = append(warnings, Warning{
+				Fields:  logrus.Fields{"resource": res.Name},
+				Message: "resource has no icon",
+			})
+		}
+	}
+
+	return warnings
+}
+
+func lintSerialGroups(config PipelineConfig) []Warning {
+	var warnings []Warning
+	for _, job := range config.Jobs {
+		if job.Serial && len(job.SerialGroups) > 0 {
+			warnings = append(warnings, Warning{
+				Fields:  logrus.Fields{"job": job.Name},
+				Message: "serial is redundant when serial_groups is set",
+			})
+		}
+	}
+
+	return warnings
+}
+
+func lintAggregate(step atc.PlanConfig) string {
+	if step.Aggregate != nil {
+		return "aggregate is deprecated; use in_parallel instead"
+	}
+
+	return ""
+}
+
+func lintPutVersionEvery(step atc.PlanConfig) string {
+	if step.Put != "" && step.Version != nil && step.Version.Every {
+		return "version: every has no effect on a put step"
+	}
+
+	return ""
+}
+
+func lintStepTags(step atc.PlanConfig) string {
+	if len(step.Tags) > 0 && step.Get == "" && step.Put == "" && step.Task == "" {
+		return "tags only apply to get, put, and task steps"
+	}
+
+	return ""
+}
+
+func lintTaskPlatform(config atc.TaskConfig) string {
+	if config.Platform == "" {
+		return "task config has no platform"
+	}
+
+	return ""
+}
@@ -0,0 +1,256 @@
+package pipe2proj
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/concourse/concourse/atc"
+	"github.com/sirupsen/logrus"
+)
+
+func TestPipelineLints(t *testing.T) {
+	for _, test := range []struct {
+		title    string
+		lint     func(PipelineConfig) []Warning
+		config   string
+		warnings []Warning
+	}{
+		{
+			title: "resources with icons",
+			lint:  lintResourceIcons,
+			config: `
+resources:
+- {name: repo, type: git, icon: github}
+`,
+		},
+		{
+			title: "resources without icons",
+			lint:  lintResourceIcons,
+			config: `
+resources:
+- {name: repo, type: git, icon: github}
+- {name: image, type: registry-image}
+- {name: bucket, type: s3}
+`,
+			warnings: []Warning{
+				{Fields: logrus.Fields{"resource": "image"}, Message: "resource has no icon"},
+				{Fields: logrus.Fields{"resource": "bucket"}, Message: "resource has no icon"},
+			},
+		},
+		{
+			title: "serial or serial groups",
+			lint:  lintSerialGroups,
+			config: `
+jobs:
+- {name: unit, serial: true, plan: [{get: repo}]}
+- {name: deploy, serial_groups: [deploy], plan: [{get: repo}]}
+`,
+		},
+		{
+			title: "serial with serial groups",
+			lint:  lintSerialGroups,
+			config: `
+jobs:
+- {name: unit, plan: [{get: repo}]}
+- {name: deploy, serial: true, serial_groups: [deploy], plan: [{get: repo}]}
+`,
+			warnings: []Warning{
+				{Fields: logrus.Fields{"job": "deploy"}, Message: "serial is redundant when serial_groups is set"},
+			},
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			config, _, err := parsePipeline([]byte(test.config), "refuse")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			warnings := test.lint(config)
+			if !reflect.DeepEqual(warnings, test.warnings) {
+				t.Errorf("expected %v, got %v", test.warnings, warnings)
+			}
+		})
+	}
+}
+
+func TestStepLints(t *testing.T) {
+	for _, test := range []struct {
+		title   string
+		lint    func(atc.PlanConfig) string
+		step    string
+		message string
+	}{
+		{
+			title:   "aggregate",
+			lint:    lintAggregate,
+			step:    "aggregate: [{get: repo}]",
+			message: "aggregate is deprecated; use in_parallel instead",
+		},
+		{
+			title: "in_parallel",
+			lint:  lintAggregate,
+			step:  "in_parallel: [{get: repo}]",
+		},
+		{
+			title:   "put with version every",
+			lint:    lintPutVersionEvery,
+			step:    "{put: repo, version: every}",
+			message: "version: every has no effect on a put step",
+		},
+		{
+			title: "put without a version",
+			lint:  lintPutVersionEvery,
+			step:  "put: repo",
+		},
+		{
+			title: "get with version every",
+			lint:  lintPutVersionEvery,
+			step:  "{get: repo, version: every}",
+		},
+		{
+			title:   "tags on a do step",
+			lint:    lintStepTags,
+			step:    "{do: [{get: repo}], tags: [linux]}",
+			message: "tags only apply to get, put, and task steps",
+		},
+		{
+			title: "tags on a get step",
+			lint:  lintStepTags,
+			step:  "{get: repo, tags: [linux]}",
+		},
+		{
+			title: "tags on a task step",
+			lint:  lintStepTags,
+			step:  "{task: unit, file: repo/ci/unit.yml, tags: [linux]}",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			message := test.lint(parsePlan(t, test.step))
+			if message != test.message {
+				t.Errorf("expected '%s', got '%s'", test.message, message)
+			}
+		})
+	}
+}
+
+func TestTaskLints(t *testing.T) {
+	for _, test := range []struct {
+		title   string
+		lint    func(atc.TaskConfig) string
+		config  atc.TaskConfig
+		message string
+	}{
+		{
+			title:  "platform",
+			lint:   lintTaskPlatform,
+			config: atc.TaskConfig{Platform: "linux"},
+		},
+		{
+			title:   "no platform",
+			lint:    lintTaskPlatform,
+			config:  atc.TaskConfig{},
+			message: "task config has no platform",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			message := test.lint(test.config)
+			if message != test.message {
+				t.Errorf("expected '%s', got '%s'", test.message, message)
+			}
+		})
+	}
+}
+
+func TestStrictLintWritesNothing(t *testing.T) {
+	for _, test := range []struct {
+		title    string
+		pipeline string
+		artifact fs.FS
+	}{
+		{
+			title: "pipeline lint",
+			pipeline: `
+resources:
+- name: repo
+  type: git
+  source: {uri: https://example.com/repo.git}
+
+jobs:
+- name: unit
+  plan:
+  - get: repo
+`,
+		},
+		{
+			title: "step lint",
+			pipeline: `
+resources:
+- name: repo
+  type: git
+  icon: git
+  source: {uri: https://example.com/repo.git}
+
+jobs:
+- name: unit
+  plan:
+  - aggregate:
+    - get: repo
+`,
+		},
+		{
+			title: "task lint",
+			pipeline: `
+resources:
+- name: repo
+  type: git
+  icon: git
+  source: {uri: https://example.com/repo.git}
+
+jobs:
+- name: unit
+  plan:
+  - get: repo
+  - task: unit
+    file: repo/ci/unit.yml
+`,
+			artifact: fstest.MapFS{
+				"ci/unit.yml": {Data: []byte("run: {path: make}\n")},
+			},
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			artifacts := map[string]fs.FS{}
+			if test.artifact != nil {
+				artifacts["repo"] = test.artifact
+			}
+
+			writer := &recordingWriter{}
+
+			_, err := Convert(Options{
+				ProjectName:   "ci",
+				PipelineName:  "main",
+				Config:        []byte(test.pipeline),
+				TaskArtifacts: artifacts,
+				Lint:          "strict",
+				Writer:        writer,
+			})
+
+			var invalid ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+
+			if len(writer.files) != 0 {
+				var paths []string
+				for _, file := range writer.files {
+					paths = append(paths, file.Path)
+				}
+
+				t.Errorf("expected nothing to be written, got %v", paths)
+			}
+		})
+	}
+}
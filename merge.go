@@ -0,0 +1,125 @@
+package pipe2proj
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/concourse/concourse/atc"
+	"github.com/sirupsen/logrus"
+)
+
+// mergePipelines merges the pipelines into a single pipeline named by the
+// options. Jobs, resources, and resource types which appear in more than one
+// are only included once, so long as they're identical, and groups of the
+// same name have their jobs and resources combined.
+func (c *converter) mergePipelines(pipelines []Pipeline) (Pipeline, error) {
+	var merged PipelineConfig
+
+	// where each job, resource, and resource type came from
+	jobSources := map[string]string{}
+	resourceSources := map[string]string{}
+	resourceTypeSources := map[string]string{}
+
+	vars := atc.Source{}
+
+	var sources []string
+	for _, pipeline := range pipelines {
+		config, pipelineVars, err := parsePipeline(pipeline.Config, c.MultiDoc)
+		if err != nil {
+			return Pipeline{}, fmt.Errorf("%s: %w", pipeline.Source, err)
+		}
+
+		for k, v := range pipelineVars {
+			vars[k] = v
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"source": pipeline.Source,
+		}).Info("merging pipeline")
+
+		sources = append(sources, pipeline.Source)
+
+		for _, job := range config.Jobs {
+			existing, found := merged.Jobs.Lookup(job.Name)
+			if !found {
+				merged.Jobs = append(merged.Jobs, job)
+				jobSources[job.Name] = pipeline.Source
+				continue
+			}
+
+			if !reflect.DeepEqual(existing, job) {
+				return Pipeline{}, invalidf("cannot merge job '%s': it differs between %s and %s", job.Name, jobSources[job.Name], pipeline.Source)
+			}
+		}
+
+		for _, res := range config.Resources {
+			existing, found := merged.Resources.Lookup(res.Name)
+			if !found {
+				merged.Resources = append(merged.Resources, res)
+				resourceSources[res.Name] = pipeline.Source
+				continue
+			}
+
+			if !reflect.DeepEqual(existing, res) {
+				return Pipeline{}, invalidf("cannot merge resource '%s': it differs between %s and %s", res.Name, resourceSources[res.Name], pipeline.Source)
+			}
+		}
+
+		for _, res := range config.ResourceTypes {
+			existing, found := merged.ResourceTypes.Lookup(res.Name)
+			if !found {
+				merged.ResourceTypes = append(merged.ResourceTypes, res)
+				resourceTypeSources[res.Name] = pipeline.Source
+				continue
+			}
+
+			if !reflect.DeepEqual(existing, res) {
+				return Pipeline{}, invalidf("cannot merge resource type '%s': it differs between %s and %s", res.Name, resourceTypeSources[res.Name], pipeline.Source)
+			}
+		}
+
+		for _, group := range config.Groups {
+			i := groupIndex(merged.Groups, group.Name)
+			if i == -1 {
+				merged.Groups = append(merged.Groups, atc.GroupConfig{Name: group.Name})
+				i = len(merged.Groups) - 1
+			}
+
+			merged.Groups[i].Jobs = unionStrings(merged.Groups[i].Jobs, group.Jobs)
+			merged.Groups[i].Resources = unionStrings(merged.Groups[i].Resources, group.Resources)
+		}
+	}
+
+	payload, err := marshalPipeline(merged, vars)
+	if err != nil {
+		return Pipeline{}, err
+	}
+
+	return Pipeline{
+		Name:   c.PipelineName,
+		Config: payload,
+		Source: strings.Join(sources, ", "),
+	}, nil
+}
+
+func groupIndex(groups atc.GroupConfigs, name string) int {
+	for i, group := range groups {
+		if group.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// unionStrings appends the strings which aren't already in the list.
+func unionStrings(list []string, more []string) []string {
+	for _, str := range more {
+		if !containsString(list, str) {
+			list = append(list, str)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,245 @@
+package pipe2proj
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/concourse/concourse/atc"
+)
+
+// Overview summarizes the converted pipelines for people new to the project:
+// the jobs and groups of each pipeline, the resources they use, and which
+// jobs run each task.
+type Overview struct {
+	Pipelines []OverviewPipeline
+	Resources []OverviewResource
+	Tasks     []OverviewTask
+}
+
+// OverviewPipeline is a converted pipeline's jobs and groups.
+type OverviewPipeline struct {
+	Name   string
+	Jobs   []string
+	Groups []string
+}
+
+// OverviewResource is a resource used by one or more of the pipelines.
+type OverviewResource struct {
+	Name string
+	Type string
+
+	// a short summary of its source, e.g. 'branch: main, uri: ...'
+	Source string
+
+	// path of its file within the project; empty with --flat
+	Path string
+
+	Pipelines []string
+}
+
+// OverviewTask is a task converted from a file, with the jobs which run it
+// as 'pipeline/job'.
+type OverviewTask struct {
+	Path   string
+	Source string
+	Jobs   []string
+}
+
+// longest source summary before it's cut short
+const maxSourceSummary = 60
+
+// source keys which are left out of summaries as they may hold credentials
+var secretSourceKeys = []string{"key", "password", "secret", "token", "credential"}
+
+// addPipeline adds the converted pipeline. Resources and tasks shared with
+// earlier pipelines are only listed once, and everything is kept sorted so
+// that the overview doesn't change unless the pipelines do.
+func (overview *Overview) addPipeline(name string, config PipelineConfig, tasks []convertedTask, resourcesPath string) {
+	pipeline := OverviewPipeline{
+		Name: name,
+	}
+
+	for _, job := range config.Jobs {
+		pipeline.Jobs = append(pipeline.Jobs, job.Name)
+	}
+
+	for _, group := range config.Groups {
+		pipeline.Groups = append(pipeline.Groups, group.Name)
+	}
+
+	overview.Pipelines = append(overview.Pipelines, pipeline)
+
+	for _, res := range config.Resources {
+		i := overview.resourceIndex(res.Name)
+		if i == -1 {
+			summary := OverviewResource{
+				Name:   res.Name,
+				Type:   res.Type,
+				Source: summarizeSource(res.Source),
+			}
+
+			if resourcesPath != "" {
+				summary.Path = filepath.Join(resourcesPath, res.Name+".yml")
+			}
+
+			overview.Resources = append(overview.Resources, summary)
+			i = len(overview.Resources) - 1
+		}
+
+		overview.Resources[i].Pipelines = unionStrings(overview.Resources[i].Pipelines, []string{name})
+	}
+
+	sort.Slice(overview.Resources, func(i, j int) bool {
+		return overview.Resources[i].Name < overview.Resources[j].Name
+	})
+
+	for _, task := range tasks {
+		i := overview.taskIndex(task.Path)
+		if i == -1 {
+			overview.Tasks = append(overview.Tasks, OverviewTask{
+				Path:   task.Path,
+				Source: task.Source,
+			})
+
+			i = len(overview.Tasks) - 1
+		}
+
+		overview.Tasks[i].Jobs = unionStrings(overview.Tasks[i].Jobs, []string{name + "/" + task.Job})
+		sort.Strings(overview.Tasks[i].Jobs)
+	}
+
+	sort.Slice(overview.Tasks, func(i, j int) bool {
+		return overview.Tasks[i].Path < overview.Tasks[j].Path
+	})
+}
+
+func (overview *Overview) resourceIndex(name string) int {
+	for i, res := range overview.Resources {
+		if res.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (overview *Overview) taskIndex(path string) int {
+	for i, task := range overview.Tasks {
+		if task.Path == path {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// summarizeSource lists the source's scalar values by key, leaving out any
+// which may be credentials, and cuts it short if it's long.
+func summarizeSource(source atc.Source) string {
+	var keys []string
+	for key, val := range source {
+		switch val.(type) {
+		case map[interface{}]interface{}, map[string]interface{}, []interface{}, nil:
+			continue
+		}
+
+		if isSecretSourceKey(key) {
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var fields []string
+	for _, key := range keys {
+		fields = append(fields, fmt.Sprintf("%s: %v", key, source[key]))
+	}
+
+	summary := strings.Join(fields, ", ")
+
+	runes := []rune(summary)
+	if len(runes) > maxSourceSummary {
+		summary = string(runes[:maxSourceSummary-3]) + "..."
+	}
+
+	return summary
+}
+
+func isSecretSourceKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretSourceKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WriteMarkdown writes the overview as Markdown tables of the pipelines,
+// resources, and tasks.
+func (overview Overview) WriteMarkdown(w io.Writer) error {
+	var out strings.Builder
+	fmt.Fprintln(&out, "# Project overview")
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "This file is generated by pipe2proj; changes to it will be overwritten.")
+
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "## Pipelines")
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "| Pipeline | Jobs | Groups |")
+	fmt.Fprintln(&out, "| --- | --- | --- |")
+	for _, pipeline := range overview.Pipelines {
+		fmt.Fprintf(&out, "| %s | %s | %s |\n", markdownCode(pipeline.Name), markdownList(pipeline.Jobs), markdownList(pipeline.Groups))
+	}
+
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "## Resources")
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "| Resource | Type | Source | File | Pipelines |")
+	fmt.Fprintln(&out, "| --- | --- | --- | --- | --- |")
+	for _, res := range overview.Resources {
+		fmt.Fprintf(&out, "| %s | %s | %s | %s | %s |\n", markdownCode(res.Name), markdownCode(res.Type), markdownCell(res.Source), markdownCode(filepath.ToSlash(res.Path)), markdownList(res.Pipelines))
+	}
+
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "## Tasks")
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "| Task | Converted from | Jobs |")
+	fmt.Fprintln(&out, "| --- | --- | --- |")
+	for _, task := range overview.Tasks {
+		fmt.Fprintf(&out, "| %s | %s | %s |\n", markdownCode(filepath.ToSlash(task.Path)), markdownCode(task.Source), markdownList(task.Jobs))
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// markdownCell escapes the text for use in a table cell, which can't span
+// lines.
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "\n", " ")
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+func markdownCode(text string) string {
+	if text == "" {
+		return ""
+	}
+
+	return "`" + markdownCell(text) + "`"
+}
+
+func markdownList(items []string) string {
+	var codes []string
+	for _, item := range items {
+		codes = append(codes, markdownCode(item))
+	}
+
+	return strings.Join(codes, ", ")
+}
@@ -0,0 +1,46 @@
+package pipe2proj
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// postRenderCmd returns the command to pipe rendered files of the given kind
+// through, if any.
+func (c *converter) postRenderCmd(kind string) string {
+	if command, found := c.PostRenderKindCmds[kind]; found {
+		return command
+	}
+
+	return c.PostRenderCmd
+}
+
+// pipeThrough runs the command with the payload on stdin and the given
+// environment variables set, returning its stdout. The command is killed if
+// the context is cancelled.
+func pipeThrough(ctx context.Context, command string, env []string, payload []byte) ([]byte, error) {
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	run := exec.CommandContext(ctx, "sh", "-c", command)
+	run.Env = append(os.Environ(), env...)
+	run.Stdin = bytes.NewBuffer(payload)
+	run.Stdout = stdout
+	run.Stderr = stderr
+
+	err := run.Run()
+	if ctx.Err() != nil {
+		// rather than 'signal: killed'
+		return nil, ctx.Err()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s\n\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,135 @@
+package pipe2proj
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/concourse/concourse/atc"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// decodeFunc decodes a file from the project, returning the template to
+// render it with.
+type decodeFunc func(name string, payload []byte) (string, interface{}, error)
+
+// PrettyPrint re-renders the files already in a project through the
+// templates, e.g. to normalize their formatting after a template change,
+// without converting the pipeline again. Each file's type is determined by
+// the directory it's in. Options which only apply to converting, e.g.
+// opts.Config, are ignored.
+func PrettyPrint(project fs.FS, opts Options) (*Result, error) {
+	c, err := newConverter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.prettyPrintProject(project)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.result, nil
+}
+
+func (c *converter) prettyPrintProject(project fs.FS) error {
+	decodeResource := func(name string, payload []byte) (string, interface{}, error) {
+		var anon AnonymousResourceConfig
+		err := yaml.UnmarshalStrict(payload, &anon)
+		if err != nil {
+			return "", nil, err
+		}
+
+		anon.ResourceName = name
+
+		return c.Templates.resourceTemplate(anon.Type), anon, nil
+	}
+
+	dirs := []struct {
+		dir    string
+		kind   string
+		decode decodeFunc
+	}{
+		{"resources", "resource", decodeResource},
+		{"resource-types", "resource-type", decodeResource},
+		{"tasks", "task", func(name string, payload []byte) (string, interface{}, error) {
+			var config atc.TaskConfig
+			err := yaml.UnmarshalStrict(payload, &config)
+			return "task.tmpl", config, err
+		}},
+		{"images", "image", decodeResource},
+		{"pipelines", "pipeline", func(name string, payload []byte) (string, interface{}, error) {
+			var config PipelineConfig
+			err := yaml.UnmarshalStrict(payload, &config)
+			return "pipeline.tmpl", config, err
+		}},
+	}
+
+	for _, d := range dirs {
+		err := fs.WalkDir(project, d.dir, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if filePath == d.dir && errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+
+				return err
+			}
+
+			if entry.IsDir() {
+				if filePath == path.Join("tasks", "scripts") {
+					return fs.SkipDir
+				}
+
+				return nil
+			}
+
+			if path.Ext(filePath) != ".yml" {
+				return nil
+			}
+
+			name := strings.TrimSuffix(strings.TrimPrefix(filePath, d.dir+"/"), ".yml")
+
+			return c.prettyPrintFile(project, filePath, d.kind, name, d.decode)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := fs.Stat(project, "project.yml")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return c.prettyPrintFile(project, "project.yml", "project", "", func(name string, payload []byte) (string, interface{}, error) {
+		var config ProjectConfig
+		err := yaml.UnmarshalStrict(payload, &config)
+		return "project.tmpl", config, err
+	})
+}
+
+// prettyPrintFile decodes the file and renders it back in place.
+func (c *converter) prettyPrintFile(project fs.FS, filePath string, kind string, name string, decode decodeFunc) error {
+	logrus.WithFields(logrus.Fields{
+		"path": filePath,
+	}).Info("pretty-printing")
+
+	payload, err := fs.ReadFile(project, filePath)
+	if err != nil {
+		return err
+	}
+
+	tmpl, val, err := decode(name, payload)
+	if err != nil {
+		return invalidf("%s: %s", filePath, explainVarTypeError(payload, err))
+	}
+
+	return c.render(GeneratedFile{
+		Path:   filePath,
+		Kind:   kind,
+		Name:   name,
+		Source: filePath,
+	}, tmpl, val)
+}
@@ -0,0 +1,38 @@
+package pipe2proj
+
+import (
+	"fmt"
+
+	"github.com/concourse/concourse/atc"
+	"gopkg.in/yaml.v2"
+)
+
+// reassemblePipeline puts the converted pipeline back together into a single
+// config as it could be set with fly, including its resources and resource
+// types, and with the config of each task converted into the project inline.
+// The configs are given by the name the task steps were given.
+func reassemblePipeline(config PipelineConfig, taskConfigs map[string]atc.TaskConfig) ([]byte, error) {
+	var jobs atc.JobConfigs
+	for _, job := range config.Jobs {
+		newJob, err := WalkJob(job, func(_ StepPath, p atc.PlanConfig) (atc.PlanConfig, error) {
+			if p.Task == "" || p.TaskConfigPath != "" || p.TaskConfig != nil {
+				return p, nil
+			}
+
+			if taskConfig, found := taskConfigs[p.Task]; found {
+				p.TaskConfig = &taskConfig
+			}
+
+			return p, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("job '%s': %w", job.Name, err)
+		}
+
+		jobs = append(jobs, newJob)
+	}
+
+	config.Jobs = jobs
+
+	return yaml.Marshal(config)
+}
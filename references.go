@@ -0,0 +1,36 @@
+package pipe2proj
+
+// referencedResources returns the set of resources used by a get or put step
+// in any of the pipeline's jobs. YAML anchors and merge keys have already
+// been expanded by the time the config is decoded, so resources and steps
+// defined through them are matched by their expanded names.
+func referencedResources(config PipelineConfig) (map[string]bool, error) {
+	graph, err := pipelineGraph("", config)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := map[string]bool{}
+	for _, edge := range graph.Edges {
+		referenced[edge.Resource] = true
+	}
+
+	return referenced, nil
+}
+
+// unusedResources returns the names of resources which no step refers to.
+func unusedResources(config PipelineConfig) ([]string, error) {
+	referenced, err := referencedResources(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var unused []string
+	for _, res := range config.Resources {
+		if !referenced[res.Name] {
+			unused = append(unused, res.Name)
+		}
+	}
+
+	return unused, nil
+}
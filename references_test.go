@@ -0,0 +1,163 @@
+package pipe2proj
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestAnchoredResources(t *testing.T) {
+	config, _, err := parsePipeline(readFixture(t, "anchors.yml"), "refuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	referenced, err := referencedResources(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]bool{"repo": true, "repo-release": true, "target": true}
+	if !reflect.DeepEqual(referenced, expected) {
+		t.Errorf("expected referenced resources %v, got %v", expected, referenced)
+	}
+
+	unused, err := unusedResources(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(unused, []string{"docs"}) {
+		t.Errorf("expected unused resources [docs], got %v", unused)
+	}
+}
+
+func TestConvertAnchoredResources(t *testing.T) {
+	result, err := Convert(Options{
+		ProjectName:         "ci",
+		PipelineName:        "main",
+		Config:              readFixture(t, "anchors.yml"),
+		TaskArtifacts:       map[string]fs.FS{"repo": ciArtifact},
+		WarnUnusedResources: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]string{
+		"resources/repo.yml": `---
+type: git
+check_every: 10m
+
+source:
+  branch: main
+  uri: https://example.com/repo.git
+`,
+		"resources/repo-release.yml": `---
+type: git
+check_every: 10m
+
+source:
+  branch: release
+  uri: https://example.com/repo.git
+`,
+		"resources/docs.yml": `---
+type: git
+check_every: 10m
+
+source:
+  branch: main
+  uri: https://example.com/repo.git
+`,
+	} {
+		payload := generatedFile(t, result, path)
+		if string(payload) != expected {
+			t.Errorf("%s: expected:\n\n%s\n\ngot:\n\n%s", path, expected, payload)
+		}
+	}
+
+	expectedWarnings := []Warning{{
+		Fields:  logrus.Fields{"resource": "docs"},
+		Message: "resource is never used",
+	}}
+
+	if !reflect.DeepEqual(result.Warnings, expectedWarnings) {
+		t.Errorf("expected warnings %v, got %v", expectedWarnings, result.Warnings)
+	}
+}
+
+func TestExcludeJobAnchoredResources(t *testing.T) {
+	result, err := Convert(Options{
+		ProjectName:         "ci",
+		PipelineName:        "main",
+		Config:              readFixture(t, "anchors.yml"),
+		TaskArtifacts:       map[string]fs.FS{"repo": ciArtifact},
+		WarnUnusedResources: true,
+		ExcludeJobs:         []string{"deploy"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the resources only the excluded job used are no longer referenced
+	var unused []string
+	for _, warning := range result.Warnings {
+		if warning.Message == "resource is never used" {
+			unused = append(unused, warning.Fields["resource"].(string))
+		}
+	}
+
+	if !reflect.DeepEqual(unused, []string{"repo-release", "docs", "target"}) {
+		t.Errorf("expected unused resources [repo-release docs target], got %v", unused)
+	}
+
+	// the passed constraint merged into the integration job still counts
+	_, err = Convert(Options{
+		ProjectName:   "ci",
+		PipelineName:  "main",
+		Config:        readFixture(t, "anchors.yml"),
+		TaskArtifacts: map[string]fs.FS{"repo": ciArtifact},
+		ExcludeJobs:   []string{"unit"},
+	})
+
+	var invalid ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected a validation error excluding a job in a passed constraint, got %v", err)
+	}
+}
+
+func TestSplitAnchoredResources(t *testing.T) {
+	result, err := Convert(Options{
+		ProjectName:   "ci",
+		Config:        readFixture(t, "anchors.yml"),
+		TaskArtifacts: map[string]fs.FS{"repo": ciArtifact},
+		SplitByGroup:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resources := map[string][]string{}
+	for _, pipeline := range result.Pipelines {
+		config, _, err := parsePipeline(pipeline.Config, "refuse")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, res := range config.Resources {
+			resources[pipeline.Name] = append(resources[pipeline.Name], res.Name)
+		}
+	}
+
+	expected := map[string][]string{
+		"build": {"repo"},
+		"ship":  {"repo", "repo-release", "target"},
+	}
+
+	if !reflect.DeepEqual(resources, expected) {
+		t.Errorf("expected resources %v, got %v", expected, resources)
+	}
+}
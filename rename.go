@@ -0,0 +1,79 @@
+package pipe2proj
+
+import (
+	"github.com/concourse/concourse/atc"
+	"github.com/sirupsen/logrus"
+)
+
+// renameResources renames resources in the config and rewrites every step
+// that refers to them. Steps that referred to a resource by its name alone
+// keep the old name as the step name so that their artifact names don't
+// change.
+func renameResources(config *PipelineConfig, renames []ResourceRename) error {
+	names := map[string]string{}
+	for _, rename := range renames {
+		if _, found := config.Resources.Lookup(rename.Old); !found {
+			return invalidf("cannot rename unknown resource '%s'", rename.Old)
+		}
+
+		if _, found := config.Resources.Lookup(rename.New); found {
+			return invalidf("cannot rename resource '%s' to '%s': resource already exists", rename.Old, rename.New)
+		}
+
+		if _, found := names[rename.Old]; found {
+			return invalidf("resource '%s' renamed more than once", rename.Old)
+		}
+
+		for old, new := range names {
+			if new == rename.New {
+				return invalidf("cannot rename both '%s' and '%s' to '%s'", old, rename.Old, rename.New)
+			}
+		}
+
+		names[rename.Old] = rename.New
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	for i, res := range config.Resources {
+		if new, found := names[res.Name]; found {
+			logrus.WithFields(logrus.Fields{
+				"old": res.Name,
+				"new": new,
+			}).Info("renaming resource")
+
+			config.Resources[i].Name = new
+		}
+	}
+
+	for i, group := range config.Groups {
+		for j, name := range group.Resources {
+			if new, found := names[name]; found {
+				config.Groups[i].Resources[j] = new
+			}
+		}
+	}
+
+	for i, job := range config.Jobs {
+		newJob, err := WalkJob(job, func(_ StepPath, p atc.PlanConfig) (atc.PlanConfig, error) {
+			if p.Get == "" && p.Put == "" {
+				return p, nil
+			}
+
+			if new, found := names[p.ResourceName()]; found {
+				p.Resource = new
+			}
+
+			return p, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		config.Jobs[i] = newJob
+	}
+
+	return nil
+}
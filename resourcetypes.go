@@ -0,0 +1,95 @@
+package pipe2proj
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// coreResourceTypes are the resource types bundled with Concourse.
+var coreResourceTypes = []string{
+	"bosh-io-release",
+	"bosh-io-stemcell",
+	"cf",
+	"docker-image",
+	"git",
+	"github-release",
+	"hg",
+	"mock",
+	"pool",
+	"registry-image",
+	"s3",
+	"semver",
+	"time",
+	"tracker",
+}
+
+// skippedResourceTypes returns the set of resource types to leave out of the
+// project, along with warnings about any resources which would be left with a
+// type that isn't provided by Concourse.
+func skippedResourceTypes(config PipelineConfig, names []string) (map[string]bool, []Warning) {
+	core := map[string]bool{}
+	for _, name := range coreResourceTypes {
+		core[name] = true
+	}
+
+	skipped := map[string]bool{}
+	for _, name := range names {
+		if _, found := config.ResourceTypes.Lookup(name); found {
+			skipped[name] = true
+		}
+	}
+
+	var warnings []Warning
+	for _, res := range config.Resources {
+		if skipped[res.Type] && !core[res.Type] {
+			warnings = append(warnings, Warning{
+				Fields: logrus.Fields{
+					"resource": res.Name,
+					"type":     res.Type,
+				},
+				Message: "resource type will no longer be defined",
+			})
+		}
+	}
+
+	for _, res := range config.ResourceTypes {
+		if !skipped[res.Name] && skipped[res.Type] && !core[res.Type] {
+			warnings = append(warnings, Warning{
+				Fields: logrus.Fields{
+					"resource-type": res.Name,
+					"type":          res.Type,
+				},
+				Message: "resource type will no longer be defined",
+			})
+		}
+	}
+
+	return skipped, warnings
+}
+
+// usedResourceTypes returns the set of types used by the pipeline's
+// resources and by the given task image types, including the types that those
+// types are themselves built from.
+func usedResourceTypes(config PipelineConfig, imageTypes []string) map[string]bool {
+	queue := append([]string{}, imageTypes...)
+	for _, res := range config.Resources {
+		queue = append(queue, res.Type)
+	}
+
+	used := map[string]bool{}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		if used[name] {
+			continue
+		}
+
+		used[name] = true
+
+		if resourceType, found := config.ResourceTypes.Lookup(name); found {
+			queue = append(queue, resourceType.Type)
+		}
+	}
+
+	return used
+}
@@ -0,0 +1,78 @@
+package pipe2proj
+
+import (
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// The fixture declares custom resource types which no resource uses; only the
+// task's image may.
+func TestTaskImageResourceTypes(t *testing.T) {
+	for _, test := range []struct {
+		title         string
+		imageType     string
+		extractImages bool
+		types         []string
+	}{
+		{
+			title:     "a custom type",
+			imageType: "custom-image",
+			types:     []string{"custom-image"},
+		},
+		{
+			title:     "a custom type built on another",
+			imageType: "layered-image",
+			types:     []string{"custom-image", "layered-image"},
+		},
+		{
+			title:         "a custom type with images extracted",
+			imageType:     "custom-image",
+			extractImages: true,
+			types:         []string{"custom-image"},
+		},
+		{
+			title:     "a core type",
+			imageType: "registry-image",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			artifact := fstest.MapFS{
+				"ci/unit.yml": {Data: []byte(`platform: linux
+image_resource:
+  type: ` + test.imageType + `
+  source: {repository: golang}
+run: {path: go}
+`)},
+			}
+
+			result, err := Convert(Options{
+				ProjectName:   "ci",
+				PipelineName:  "main",
+				Config:        readFixture(t, "task-image-types.yml"),
+				TaskArtifacts: map[string]fs.FS{"repo": artifact},
+				ExtractImages: test.extractImages,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var types []string
+			for _, file := range result.Files {
+				if strings.HasPrefix(file.Path, "resource-types/") {
+					types = append(types, file.Name)
+				}
+			}
+
+			if !reflect.DeepEqual(types, test.types) {
+				t.Errorf("expected resource types %v, got %v", test.types, types)
+			}
+
+			for _, name := range test.types {
+				generatedFile(t, result, "resource-types/"+name+".yml")
+			}
+		})
+	}
+}
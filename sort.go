@@ -0,0 +1,106 @@
+package pipe2proj
+
+import (
+	"sort"
+
+	"github.com/concourse/concourse/atc"
+)
+
+// sortConfig sorts resources, resource types, and groups by name. Jobs are
+// sorted by name, or with "deps" ordering, so that each job comes after the
+// jobs its inputs pass through (breaking ties by name).
+func sortConfig(config *PipelineConfig, order string) error {
+	sortResources(config)
+
+	sort.SliceStable(config.Groups, func(i, j int) bool {
+		return config.Groups[i].Name < config.Groups[j].Name
+	})
+
+	sortJobs(config)
+
+	if order != "deps" {
+		return nil
+	}
+
+	deps := map[string]map[string]bool{}
+	for _, job := range config.Jobs {
+		jobDeps := map[string]bool{}
+
+		err := VisitJob(job, func(_ StepPath, p atc.PlanConfig) error {
+			for _, passed := range p.Passed {
+				if _, found := config.Jobs.Lookup(passed); found && passed != job.Name {
+					jobDeps[passed] = true
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		deps[job.Name] = jobDeps
+	}
+
+	sorted := atc.JobConfigs{}
+	placed := map[string]bool{}
+	for len(sorted) < len(config.Jobs) {
+		progress := false
+
+		// jobs are already sorted by name, so the first ready job is the
+		// tie-breaker
+		for _, job := range config.Jobs {
+			if placed[job.Name] || !satisfied(deps[job.Name], placed) {
+				continue
+			}
+
+			sorted = append(sorted, job)
+			placed[job.Name] = true
+			progress = true
+
+			break
+		}
+
+		if !progress {
+			// a cycle; keep the rest in name order
+			for _, job := range config.Jobs {
+				if !placed[job.Name] {
+					sorted = append(sorted, job)
+					placed[job.Name] = true
+				}
+			}
+		}
+	}
+
+	config.Jobs = sorted
+
+	return nil
+}
+
+// sortResources sorts resources and resource types by name.
+func sortResources(config *PipelineConfig) {
+	sort.SliceStable(config.Resources, func(i, j int) bool {
+		return config.Resources[i].Name < config.Resources[j].Name
+	})
+
+	sort.SliceStable(config.ResourceTypes, func(i, j int) bool {
+		return config.ResourceTypes[i].Name < config.ResourceTypes[j].Name
+	})
+}
+
+// sortJobs sorts jobs by name.
+func sortJobs(config *PipelineConfig) {
+	sort.SliceStable(config.Jobs, func(i, j int) bool {
+		return config.Jobs[i].Name < config.Jobs[j].Name
+	})
+}
+
+func satisfied(deps map[string]bool, placed map[string]bool) bool {
+	for dep := range deps {
+		if !placed[dep] {
+			return false
+		}
+	}
+
+	return true
+}
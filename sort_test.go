@@ -0,0 +1,129 @@
+package pipe2proj
+
+import (
+	"reflect"
+	"testing"
+)
+
+const unsortedPipeline = `
+resource_types:
+- name: slack
+  type: registry-image
+  source: {repository: example/slack-resource}
+- name: pr
+  type: registry-image
+  source: {repository: example/pr-resource}
+
+resources:
+- name: repo
+  type: git
+  source: {uri: https://example.com/repo.git}
+- name: notify
+  type: slack
+  source: {url: https://hooks.example.com/ci}
+- name: pull-requests
+  type: pr
+  source: {repository: example/repo}
+
+jobs:
+- name: unit
+  plan:
+  - get: repo
+- name: deploy
+  plan:
+  - get: repo
+  - put: notify
+- name: check-pr
+  plan:
+  - get: pull-requests
+`
+
+func TestSortJobsAndResources(t *testing.T) {
+	sourceJobs := []string{"unit", "deploy", "check-pr"}
+	sortedJobs := []string{"check-pr", "deploy", "unit"}
+
+	sourceResources := []string{"repo", "notify", "pull-requests"}
+	sortedResources := []string{"notify", "pull-requests", "repo"}
+
+	sourceTypes := []string{"slack", "pr"}
+	sortedTypes := []string{"pr", "slack"}
+
+	for _, test := range []struct {
+		title         string
+		sortJobs      bool
+		sortResources bool
+		jobs          []string
+		resources     []string
+		resourceTypes []string
+	}{
+		{
+			title:         "source order by default",
+			jobs:          sourceJobs,
+			resources:     sourceResources,
+			resourceTypes: sourceTypes,
+		},
+		{
+			title:         "sorting jobs",
+			sortJobs:      true,
+			jobs:          sortedJobs,
+			resources:     sourceResources,
+			resourceTypes: sourceTypes,
+		},
+		{
+			title:         "sorting resources",
+			sortResources: true,
+			jobs:          sourceJobs,
+			resources:     sortedResources,
+			resourceTypes: sortedTypes,
+		},
+		{
+			title:         "sorting both",
+			sortJobs:      true,
+			sortResources: true,
+			jobs:          sortedJobs,
+			resources:     sortedResources,
+			resourceTypes: sortedTypes,
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			result, err := Convert(Options{
+				ProjectName:   "ci",
+				PipelineName:  "main",
+				Config:        []byte(unsortedPipeline),
+				Flat:          true,
+				SortJobs:      test.sortJobs,
+				SortResources: test.sortResources,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			converted := convertedPipeline(t, result, "main")
+
+			var jobs, resources, resourceTypes []string
+			for _, job := range converted.Jobs {
+				jobs = append(jobs, job.Name)
+			}
+
+			for _, res := range converted.Resources {
+				resources = append(resources, res.Name)
+			}
+
+			for _, res := range converted.ResourceTypes {
+				resourceTypes = append(resourceTypes, res.Name)
+			}
+
+			if !reflect.DeepEqual(jobs, test.jobs) {
+				t.Errorf("expected jobs %v, got %v", test.jobs, jobs)
+			}
+
+			if !reflect.DeepEqual(resources, test.resources) {
+				t.Errorf("expected resources %v, got %v", test.resources, resources)
+			}
+
+			if !reflect.DeepEqual(resourceTypes, test.resourceTypes) {
+				t.Errorf("expected resource types %v, got %v", test.resourceTypes, resourceTypes)
+			}
+		})
+	}
+}
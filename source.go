@@ -0,0 +1,145 @@
+package pipe2proj
+
+import (
+	"strings"
+
+	"github.com/concourse/concourse/atc"
+	"github.com/sirupsen/logrus"
+)
+
+// rewriteSources applies each rewrite to the sources of resources and
+// resource types of the rewrite's type. Only string values are rewritten.
+func rewriteSources(config *PipelineConfig, rewrites []SourceRewrite) {
+	for _, rewrite := range rewrites {
+		for _, res := range config.Resources {
+			if res.Type == rewrite.Type && rewriteSource(res.Source, rewrite) {
+				logrus.WithFields(logrus.Fields{
+					"resource": res.Name,
+					"key":      strings.Join(rewrite.Key, "."),
+				}).Info("rewrote source")
+			}
+		}
+
+		for _, res := range config.ResourceTypes {
+			if res.Type == rewrite.Type && rewriteSource(res.Source, rewrite) {
+				logrus.WithFields(logrus.Fields{
+					"resource-type": res.Name,
+					"key":           strings.Join(rewrite.Key, "."),
+				}).Info("rewrote source")
+			}
+		}
+	}
+}
+
+// overrideSources sets each override's value in the source of its resource,
+// adding the key if it isn't there.
+func overrideSources(config *PipelineConfig, overrides []SourceOverride) error {
+	for _, override := range overrides {
+		found := false
+		for i, res := range config.Resources {
+			if res.Name != override.Resource {
+				continue
+			}
+
+			if res.Source == nil {
+				config.Resources[i].Source = atc.Source{}
+			}
+
+			setSourceValue(config.Resources[i].Source, override.Key, override.Value)
+
+			logrus.WithFields(logrus.Fields{
+				"resource": res.Name,
+				"key":      strings.Join(override.Key, "."),
+			}).Info("overrode source")
+
+			found = true
+		}
+
+		if !found {
+			return invalidf("cannot override source of unknown resource '%s'", override.Resource)
+		}
+	}
+
+	return nil
+}
+
+func rewriteSource(source atc.Source, rewrite SourceRewrite) bool {
+	val, found := sourceValue(source, rewrite.Key)
+	if !found {
+		return false
+	}
+
+	str, ok := val.(string)
+	if !ok {
+		return false
+	}
+
+	rewritten := rewrite.Pattern.ReplaceAllString(str, rewrite.Replacement)
+	if rewritten == str {
+		return false
+	}
+
+	setSourceValue(source, rewrite.Key, rewritten)
+
+	return true
+}
+
+// sourceValue fetches the value at the given path in the source, traversing
+// nested maps.
+func sourceValue(source atc.Source, path []string) (interface{}, bool) {
+	var val interface{} = map[string]interface{}(source)
+	for _, key := range path {
+		var found bool
+		val, found = mapValue(val, key)
+		if !found {
+			return nil, false
+		}
+	}
+
+	return val, true
+}
+
+// setSourceValue sets the value at the given path in the source, creating
+// intermediate maps as necessary.
+func setSourceValue(source atc.Source, path []string, val interface{}) {
+	var parent interface{} = map[string]interface{}(source)
+	for _, key := range path[:len(path)-1] {
+		child, _ := mapValue(parent, key)
+
+		switch child.(type) {
+		case map[string]interface{}, map[interface{}]interface{}:
+		default:
+			child = map[interface{}]interface{}{}
+			setMapValue(parent, key, child)
+		}
+
+		parent = child
+	}
+
+	setMapValue(parent, path[len(path)-1], val)
+}
+
+// mapValue and setMapValue handle both the map[string]interface{} at the top
+// of a source and the map[interface{}]interface{} that YAML produces for
+// nested maps.
+func mapValue(m interface{}, key string) (interface{}, bool) {
+	switch m := m.(type) {
+	case map[string]interface{}:
+		val, found := m[key]
+		return val, found
+	case map[interface{}]interface{}:
+		val, found := m[key]
+		return val, found
+	default:
+		return nil, false
+	}
+}
+
+func setMapValue(m interface{}, key string, val interface{}) {
+	switch m := m.(type) {
+	case map[string]interface{}:
+		m[key] = val
+	case map[interface{}]interface{}:
+		m[key] = val
+	}
+}
@@ -0,0 +1,157 @@
+package pipe2proj
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+const overriddenPipeline = `
+resources:
+- name: image
+  type: registry-image
+  source:
+    repository: registry.dev.example.com/app
+    auth:
+      username: dev
+      password: ((registry.password))
+
+- name: notify
+  type: slack-notification
+  source:
+    url: https://hooks.example.com/dev
+
+jobs:
+- name: unit
+  plan:
+  - get: image
+  - put: notify
+`
+
+func TestSetSource(t *testing.T) {
+	for _, test := range []struct {
+		title     string
+		overrides []string
+		resource  string
+		expected  string
+		invalid   bool
+	}{
+		{
+			title:     "overwriting a nested value",
+			overrides: []string{"image.auth.username=prod"},
+			resource:  "image",
+			expected: `---
+type: registry-image
+
+source:
+  auth:
+    password: ((registry.password))
+    username: prod
+  repository: registry.dev.example.com/app
+`,
+		},
+		{
+			title:     "adding a nested value",
+			overrides: []string{"image.auth.email=ci@example.com", "image.repository=registry.example.com/app"},
+			resource:  "image",
+			expected: `---
+type: registry-image
+
+source:
+  auth:
+    email: ci@example.com
+    password: ((registry.password))
+    username: dev
+  repository: registry.example.com/app
+`,
+		},
+		{
+			title:     "adding nested maps along the path",
+			overrides: []string{"notify.proxy.https.host=proxy.example.com"},
+			resource:  "notify",
+			expected: `---
+type: slack-notification
+
+source:
+  proxy:
+    https:
+      host: proxy.example.com
+  url: https://hooks.example.com/dev
+`,
+		},
+		{
+			title:     "an unknown resource",
+			overrides: []string{"repo.uri=https://example.com/repo.git"},
+			invalid:   true,
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			var overrides []SourceOverride
+			for _, flag := range test.overrides {
+				var override SourceOverride
+				err := override.UnmarshalFlag(flag)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				overrides = append(overrides, override)
+			}
+
+			result, err := Convert(Options{
+				ProjectName:   "ci",
+				PipelineName:  "main",
+				Config:        []byte(overriddenPipeline),
+				TaskArtifacts: map[string]fs.FS{},
+				SetSources:    overrides,
+			})
+			if test.invalid {
+				var invalid ValidationError
+				if !errors.As(err, &invalid) {
+					t.Fatalf("expected a validation error, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			payload := generatedFile(t, result, "resources/"+test.resource+".yml")
+			if string(payload) != test.expected {
+				t.Errorf("expected:\n\n%s\n\ngot:\n\n%s", test.expected, payload)
+			}
+		})
+	}
+}
+
+func TestSourceOverrideFlag(t *testing.T) {
+	for _, value := range []string{
+		"image",
+		"image=prod",
+		".auth.username=prod",
+		"image.=prod",
+		"image.auth..username=prod",
+	} {
+		var override SourceOverride
+		err := override.UnmarshalFlag(value)
+		if err == nil {
+			t.Errorf("expected '%s' to be rejected, got %#v", value, override)
+		}
+	}
+
+	var override SourceOverride
+	err := override.UnmarshalFlag("image.auth.username=a=b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	marshalled, err := override.MarshalFlag()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if marshalled != "image.auth.username=a=b" {
+		t.Errorf("expected the flag to round-trip, got '%s'", marshalled)
+	}
+}
@@ -0,0 +1,162 @@
+package pipe2proj
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/concourse/concourse/atc"
+	"github.com/sirupsen/logrus"
+)
+
+// splitPipeline splits the pipeline into one pipeline per group, named after
+// the group, each with only the group's jobs and the resources they use.
+func (c *converter) splitPipeline(pipeline Pipeline) ([]Pipeline, error) {
+	config, vars, err := parsePipeline(pipeline.Config, c.MultiDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.prepareConfig(&config)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := splitByGroup(config, c.DefaultGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	var pipelines []Pipeline
+	for _, group := range groups {
+		payload, err := marshalPipeline(group.Config, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		pipelines = append(pipelines, Pipeline{
+			Name:   group.Name,
+			Config: payload,
+			Source: pipeline.Source,
+		})
+	}
+
+	return pipelines, nil
+}
+
+// groupConfig is the part of a pipeline belonging to one of its groups.
+type groupConfig struct {
+	Name   string
+	Config PipelineConfig
+}
+
+// splitByGroup splits the pipeline by its groups. Each job must be in
+// exactly one group, or in none if there's a default group to put it in, and
+// passed constraints can't cross from one group to another since they can't
+// cross pipelines.
+func splitByGroup(config PipelineConfig, defaultGroup string) ([]groupConfig, error) {
+	if len(config.Groups) == 0 && defaultGroup == "" {
+		return nil, invalidf("cannot split a pipeline with no groups; pass --default-group to put every job in one")
+	}
+
+	jobGroups := map[string]string{}
+	for _, group := range config.Groups {
+		for _, name := range group.Jobs {
+			if other, found := jobGroups[name]; found && other != group.Name {
+				return nil, invalidf("cannot split by group: job '%s' is in both group '%s' and group '%s'", name, other, group.Name)
+			}
+
+			jobGroups[name] = group.Name
+		}
+	}
+
+	var names []string
+	for _, group := range config.Groups {
+		names = append(names, group.Name)
+	}
+
+	for _, job := range config.Jobs {
+		if _, found := jobGroups[job.Name]; found {
+			continue
+		}
+
+		if defaultGroup == "" {
+			return nil, invalidf("cannot split by group: job '%s' is not in any group; pass --default-group to put it in one", job.Name)
+		}
+
+		jobGroups[job.Name] = defaultGroup
+
+		if !containsString(names, defaultGroup) {
+			names = append(names, defaultGroup)
+		}
+	}
+
+	var blockers []string
+	for _, job := range config.Jobs {
+		err := VisitJob(job, func(path StepPath, p atc.PlanConfig) error {
+			for _, passed := range p.Passed {
+				if jobGroups[passed] != jobGroups[job.Name] {
+					blockers = append(blockers, fmt.Sprintf("job '%s' in group '%s' has job '%s' in group '%s' in a passed constraint at %s", job.Name, jobGroups[job.Name], passed, jobGroups[passed], path))
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("job '%s': %w", job.Name, err)
+		}
+	}
+
+	if len(blockers) > 0 {
+		return nil, invalidf("cannot split by group; passed constraints can't cross pipelines:\n  %s", strings.Join(blockers, "\n  "))
+	}
+
+	var groups []groupConfig
+	for _, name := range names {
+		split := PipelineConfig{
+			ResourceTypes: config.ResourceTypes,
+		}
+
+		for _, job := range config.Jobs {
+			if jobGroups[job.Name] == name {
+				split.Jobs = append(split.Jobs, job)
+			}
+		}
+
+		if len(split.Jobs) == 0 {
+			logrus.WithFields(logrus.Fields{
+				"group": name,
+			}).Info("skipping group with no jobs")
+
+			continue
+		}
+
+		graph, err := pipelineGraph(name, split)
+		if err != nil {
+			return nil, err
+		}
+
+		used := map[string]bool{}
+		for _, edge := range graph.Edges {
+			used[edge.Resource] = true
+		}
+
+		for _, res := range config.Resources {
+			if used[res.Name] {
+				split.Resources = append(split.Resources, res)
+			}
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"group":     name,
+			"jobs":      len(split.Jobs),
+			"resources": len(split.Resources),
+		}).Info("splitting group into pipeline")
+
+		groups = append(groups, groupConfig{
+			Name:   name,
+			Config: split,
+		})
+	}
+
+	return groups, nil
+}
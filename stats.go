@@ -0,0 +1,123 @@
+package pipe2proj
+
+import (
+	"bytes"
+
+	"github.com/concourse/concourse/atc"
+)
+
+// Stats counts what was converted, e.g. to compare a project with the
+// pipelines it came from.
+type Stats struct {
+	Pipelines int `json:"pipelines"`
+	Jobs      int `json:"jobs"`
+
+	// steps of the converted jobs by type, including hooks and nested steps
+	Steps map[string]int `json:"steps"`
+
+	// task steps whose config was converted into the project, those which
+	// still refer to a file, e.g. in an artifact which wasn't given, and those
+	// with their config inline
+	TasksConverted int `json:"tasks_converted"`
+	TaskFiles      int `json:"task_files"`
+	InlineTasks    int `json:"inline_tasks"`
+
+	ScriptsCopied int `json:"scripts_copied"`
+
+	// resources by type, each counted once however many pipelines use it
+	Resources map[string]int `json:"resources"`
+
+	// lines in the pipeline configs given, and in the YAML files generated
+	LinesBefore int `json:"lines_before"`
+	LinesAfter  int `json:"lines_after"`
+
+	resourceNames map[string]bool
+}
+
+// addPipeline counts the converted pipeline's jobs, steps, and resources.
+func (stats *Stats) addPipeline(config PipelineConfig) error {
+	if stats.Steps == nil {
+		stats.Steps = map[string]int{}
+		stats.Resources = map[string]int{}
+		stats.resourceNames = map[string]bool{}
+	}
+
+	stats.Pipelines++
+	stats.Jobs += len(config.Jobs)
+
+	for _, job := range config.Jobs {
+		err := VisitJob(job, func(_ StepPath, p atc.PlanConfig) error {
+			stats.Steps[stepType(p)]++
+
+			if p.Task != "" {
+				switch {
+				case p.TaskConfigPath != "":
+					stats.TaskFiles++
+				case p.TaskConfig != nil:
+					stats.InlineTasks++
+				default:
+					stats.TasksConverted++
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, res := range config.Resources {
+		if !stats.resourceNames[res.Name] {
+			stats.resourceNames[res.Name] = true
+			stats.Resources[res.Type]++
+		}
+	}
+
+	return nil
+}
+
+// addFiles counts the scripts copied into the project and the lines of the
+// YAML files generated for it.
+func (stats *Stats) addFiles(files []GeneratedFile) {
+	for _, file := range files {
+		switch file.Kind {
+		case "script":
+			stats.ScriptsCopied++
+		case "fly-script":
+		default:
+			stats.LinesAfter += countLines(file.Payload)
+		}
+	}
+}
+
+func countLines(payload []byte) int {
+	lines := bytes.Count(payload, []byte("\n"))
+	if len(payload) > 0 && !bytes.HasSuffix(payload, []byte("\n")) {
+		lines++
+	}
+
+	return lines
+}
+
+// stepType names the type of the step, e.g. 'get' or 'in_parallel'.
+func stepType(plan atc.PlanConfig) string {
+	switch {
+	case plan.Try != nil:
+		return "try"
+	case plan.Do != nil:
+		return "do"
+	case plan.Aggregate != nil:
+		return "aggregate"
+	case plan.InParallel != nil:
+		return "in_parallel"
+	case plan.Get != "":
+		return "get"
+	case plan.Put != "":
+		return "put"
+	case plan.Task != "":
+		return "task"
+	default:
+		return "unknown"
+	}
+}
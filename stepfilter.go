@@ -0,0 +1,40 @@
+package pipe2proj
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/concourse/concourse/atc"
+	"gopkg.in/yaml.v2"
+)
+
+// filterStep pipes the step through the command as YAML, replacing it with
+// the step the command prints. The step is left as-is if the command prints
+// nothing.
+func filterStep(ctx context.Context, command string, job string, path StepPath, step atc.PlanConfig) (atc.PlanConfig, error) {
+	payload, err := yaml.Marshal(step)
+	if err != nil {
+		return atc.PlanConfig{}, err
+	}
+
+	filtered, err := pipeThrough(ctx, command, []string{
+		"P2P_JOB=" + job,
+		"P2P_STEP_PATH=" + string(path),
+	}, payload)
+	if err != nil {
+		return atc.PlanConfig{}, fmt.Errorf("%s: step filter '%s' failed: %w", path, command, err)
+	}
+
+	if len(bytes.TrimSpace(filtered)) == 0 {
+		return step, nil
+	}
+
+	var newStep atc.PlanConfig
+	err = yaml.Unmarshal(filtered, &newStep)
+	if err != nil {
+		return atc.PlanConfig{}, fmt.Errorf("%s: step filter '%s' printed an invalid step: %s", path, command, err)
+	}
+
+	return newStep, nil
+}
@@ -0,0 +1,361 @@
+package pipe2proj
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+)
+
+const defaultYAMLIndent = 2
+
+// yamlStyle re-emits a rendered document with a different indent width,
+// optionally indenting sequence entries beneath their parent key and sorting
+// mapping keys. Scalar styles, comments, blank lines, and a leading document
+// marker are kept as they were.
+type yamlStyle struct {
+	Indent          int
+	IndentSequences bool
+	SortKeys        bool
+
+	// lines of the original document, for finding blank lines
+	source []string
+
+	buf *bytes.Buffer
+}
+
+func restyleYAML(payload []byte, indent int, indentSequences bool, sortKeys bool) ([]byte, error) {
+	var doc yamlv3.Node
+	err := yamlv3.Unmarshal(payload, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	style := &yamlStyle{
+		Indent:          indent,
+		IndentSequences: indentSequences,
+		SortKeys:        sortKeys,
+
+		source: strings.Split(string(payload), "\n"),
+
+		buf: new(bytes.Buffer),
+	}
+
+	if bytes.HasPrefix(payload, []byte("---")) {
+		fmt.Fprintln(style.buf, "---")
+	}
+
+	if doc.Kind == 0 || isEmptyDocument(&doc) {
+		return style.buf.Bytes(), nil
+	}
+
+	if doc.HeadComment != "" {
+		// yaml.v3 only takes a comment to head the document, rather than its
+		// first entry, when a blank line separates them
+		style.comment(doc.HeadComment, 0)
+		style.buf.WriteString("\n")
+	}
+
+	for _, node := range doc.Content {
+		err := style.block(node, 0, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	style.comment(doc.FootComment, 0)
+
+	return style.buf.Bytes(), nil
+}
+
+// block emits a node at the given column. If inline is true, the cursor is
+// already at the column, e.g. just after a sequence entry's dash.
+func (style *yamlStyle) block(node *yamlv3.Node, col int, inline bool) error {
+	if !isBlock(node) {
+		val, err := style.scalar(node, col)
+		if err != nil {
+			return err
+		}
+
+		if !inline {
+			style.pad(col)
+		}
+
+		fmt.Fprintf(style.buf, "%s%s\n", val, trailing(node.LineComment))
+
+		return nil
+	}
+
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		if style.SortKeys {
+			sortMapping(node)
+		}
+
+		for i := 0; i < len(node.Content); i += 2 {
+			key, val := node.Content[i], node.Content[i+1]
+
+			if i > 0 || !inline {
+				if i > 0 {
+					style.gap(key)
+				}
+
+				style.comment(key.HeadComment, col)
+				style.pad(col)
+			}
+
+			k, err := style.scalar(key, col)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(style.buf, "%s:", k)
+
+			err = style.value(val, col, trailing(key.LineComment, val.LineComment))
+			if err != nil {
+				return err
+			}
+
+			style.comment(key.FootComment, col)
+		}
+
+	case yamlv3.SequenceNode:
+		for i, item := range node.Content {
+			if i > 0 || !inline {
+				if i > 0 {
+					style.gap(item)
+				}
+
+				style.comment(item.HeadComment, col)
+				style.pad(col)
+			}
+
+			style.buf.WriteString("-")
+
+			if !isBlock(item) {
+				// continuation lines are relative to the dash
+				val, err := style.scalar(item, col)
+				if err != nil {
+					return err
+				}
+
+				fmt.Fprintf(style.buf, " %s%s\n", val, trailing(item.LineComment))
+				style.comment(item.FootComment, col)
+
+				continue
+			}
+
+			if item.Anchor != "" || item.LineComment != "" {
+				style.props(item.Anchor, trailing(item.LineComment))
+				style.buf.WriteString("\n")
+
+				err := style.block(item, col+2, false)
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			style.buf.WriteString(" ")
+
+			err := style.block(item, col+2, true)
+			if err != nil {
+				return err
+			}
+
+			style.comment(item.FootComment, col)
+		}
+	}
+
+	return nil
+}
+
+// value emits a mapping value following its key.
+func (style *yamlStyle) value(val *yamlv3.Node, col int, comment string) error {
+	if !isBlock(val) {
+		v, err := style.scalar(val, col)
+		if err != nil {
+			return err
+		}
+
+		if v != "" {
+			v = " " + v
+		}
+
+		fmt.Fprintf(style.buf, "%s%s\n", v, comment)
+
+		return nil
+	}
+
+	style.props(val.Anchor, comment)
+	style.buf.WriteString("\n")
+
+	if val.Kind == yamlv3.SequenceNode && !style.IndentSequences {
+		return style.block(val, col, false)
+	}
+
+	return style.block(val, col+style.Indent, false)
+}
+
+// props emits a block collection's anchor and trailing comment.
+func (style *yamlStyle) props(anchor string, comment string) {
+	if anchor != "" {
+		fmt.Fprintf(style.buf, " &%s", anchor)
+	}
+
+	style.buf.WriteString(comment)
+}
+
+// trailing joins line comments for emitting at the end of a line.
+func trailing(comments ...string) string {
+	var joined string
+	for _, comment := range comments {
+		if comment != "" {
+			joined += " " + comment
+		}
+	}
+
+	return joined
+}
+
+// scalar renders a scalar, alias, or flow collection, re-indenting any lines
+// after the first relative to the given column.
+func (style *yamlStyle) scalar(node *yamlv3.Node, col int) (string, error) {
+	if node.Kind == yamlv3.AliasNode {
+		return "*" + node.Value, nil
+	}
+
+	if node.Tag == "!!merge" {
+		// yaml.v3 would spell out the tag
+		return node.Value, nil
+	}
+
+	// comments are emitted by the caller
+	bare := *node
+	bare.HeadComment = ""
+	bare.LineComment = ""
+	bare.FootComment = ""
+
+	payload, err := yamlv3.Marshal(&bare)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(payload), "\n"), "\n")
+
+	header := lines[0]
+	if strings.HasPrefix(header, "|") || strings.HasPrefix(header, ">") {
+		if strings.ContainsAny(header, "123456789") {
+			// an explicit indentation indicator is relative to the parent,
+			// which has moved; fall back to a quoted string instead
+			quoted := bare
+			quoted.Style = yamlv3.DoubleQuotedStyle
+			return style.scalar(&quoted, col)
+		}
+
+		// yaml.v3 indents block scalar content by 4
+		for i := 1; i < len(lines); i++ {
+			lines[i] = strings.TrimPrefix(lines[i], "    ")
+		}
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = strings.Repeat(" ", col+style.Indent) + lines[i]
+		}
+	}
+
+	if node.Anchor != "" && !strings.HasPrefix(lines[0], "&") {
+		lines[0] = "&" + node.Anchor + " " + lines[0]
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// gap emits any blank lines the original document had before the node or its
+// comment.
+func (style *yamlStyle) gap(node *yamlv3.Node) {
+	line := node.Line - 1
+	if node.HeadComment != "" {
+		line -= strings.Count(node.HeadComment, "\n") + 1
+	}
+
+	// node lines are 1-indexed
+	var blanks int
+	for ; line >= 1 && line <= len(style.source); line-- {
+		if strings.TrimSpace(style.source[line-1]) != "" {
+			break
+		}
+
+		blanks++
+	}
+
+	// a kept block scalar may already end in the blank lines
+	emitted := style.buf.Bytes()
+	for len(emitted) > 1 && bytes.HasSuffix(emitted, []byte("\n\n")) && blanks > 0 {
+		emitted = emitted[:len(emitted)-1]
+		blanks--
+	}
+
+	style.buf.WriteString(strings.Repeat("\n", blanks))
+}
+
+func (style *yamlStyle) comment(comment string, col int) {
+	if comment == "" {
+		return
+	}
+
+	for _, line := range strings.Split(comment, "\n") {
+		if line != "" {
+			style.pad(col)
+		}
+
+		fmt.Fprintln(style.buf, line)
+	}
+}
+
+func (style *yamlStyle) pad(col int) {
+	style.buf.WriteString(strings.Repeat(" ", col))
+}
+
+// sortMapping sorts a mapping node's pairs by key. Comments move with the
+// keys they're on.
+func sortMapping(node *yamlv3.Node) {
+	pairs := make([][2]*yamlv3.Node, len(node.Content)/2)
+	for i := range pairs {
+		pairs[i] = [2]*yamlv3.Node{node.Content[2*i], node.Content[2*i+1]}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i][0].Value < pairs[j][0].Value
+	})
+
+	for i, pair := range pairs {
+		node.Content[2*i] = pair[0]
+		node.Content[2*i+1] = pair[1]
+	}
+}
+
+// isEmptyDocument reports whether the document has no content, e.g. just a
+// document marker.
+func isEmptyDocument(doc *yamlv3.Node) bool {
+	if len(doc.Content) != 1 {
+		return false
+	}
+
+	node := doc.Content[0]
+	return node.Kind == yamlv3.ScalarNode && node.Tag == "!!null" && node.Value == "" && node.HeadComment == "" && node.LineComment == "" && node.FootComment == ""
+}
+
+// isBlock reports whether the node is a non-empty collection in block style.
+func isBlock(node *yamlv3.Node) bool {
+	switch node.Kind {
+	case yamlv3.MappingNode, yamlv3.SequenceNode:
+		return node.Style&yamlv3.FlowStyle == 0 && len(node.Content) > 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,94 @@
+package pipe2proj
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// checkGolden compares the payload with the golden file, or rewrites it with
+// -update.
+func checkGolden(t *testing.T, path string, payload []byte) {
+	t.Helper()
+
+	if *updateGolden {
+		err := os.WriteFile(path, payload, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(payload) != string(expected) {
+		t.Errorf("output differs from %s:\n\nexpected:\n\n%s\n\ngot:\n\n%s", path, expected, payload)
+	}
+}
+
+func TestRestyleYAML(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "style", "*.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	styles := []struct {
+		name            string
+		indent          int
+		indentSequences bool
+		sortKeys        bool
+	}{
+		{name: "indent-4", indent: 4},
+		{name: "indent-sequences", indent: 2, indentSequences: true},
+		{name: "indent-4-sequences", indent: 4, indentSequences: true},
+		{name: "sort-keys", indent: 2, sortKeys: true},
+	}
+
+	for _, input := range inputs {
+		payload, err := os.ReadFile(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		name := strings.TrimSuffix(filepath.Base(input), ".yml")
+
+		for _, style := range styles {
+			style := style
+
+			t.Run(name+"/"+style.name, func(t *testing.T) {
+				restyled, err := restyleYAML(payload, style.indent, style.indentSequences, style.sortKeys)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var before, after interface{}
+				err = yaml.Unmarshal(payload, &before)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				err = yaml.Unmarshal(restyled, &after)
+				if err != nil {
+					t.Fatalf("restyled into invalid YAML: %s\n\n%s", err, restyled)
+				}
+
+				if !reflect.DeepEqual(before, after) {
+					t.Errorf("restyled value differs:\n\n%s", restyled)
+				}
+
+				checkGolden(t, filepath.Join("testdata", "style", "golden", name+"."+style.name+".yml"), restyled)
+			})
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package pipe2proj
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/concourse/concourse/atc"
+)
+
+// templateSample is a representative value to render a template with when
+// validating templates.
+type templateSample struct {
+	File     GeneratedFile
+	Template string
+	Value    interface{}
+}
+
+func templateSamples(templates *Templates) []templateSample {
+	resource := AnonymousResourceConfig{
+		ResourceName: "repo",
+		Type:         "git",
+		Icon:         "github",
+		CheckEvery:   "10m",
+		Source: atc.Source{
+			"uri":    "https://github.com/concourse/concourse",
+			"branch": "master",
+			"paths":  []interface{}{"ci/*", "go.mod"},
+		},
+	}
+
+	resourceType := AnonymousResourceConfig{
+		ResourceName: "slack-notification",
+		Type:         "registry-image",
+		Source: atc.Source{
+			"repository": "cfcommunity/slack-notification-resource",
+			"tag":        "latest",
+		},
+	}
+
+	task := atc.TaskConfig{
+		Platform: "linux",
+		ImageResource: &atc.ImageResource{
+			Type:   "registry-image",
+			Source: atc.Source{"repository": "golang"},
+		},
+		Params: map[string]string{
+			"GOFLAGS": "-mod=vendor",
+			"VERBOSE": "true",
+			"SCRIPT":  "echo hello\necho world\n",
+		},
+		Inputs: []atc.TaskInputConfig{
+			{Name: "ci"},
+			{Name: "repo", Path: "src", Optional: true},
+		},
+		Outputs: []atc.TaskOutputConfig{
+			{Name: "built"},
+		},
+		Run: atc.TaskRunConfig{
+			Path: "ci/tasks/scripts/build",
+			Args: []string{"-v", "--race"},
+		},
+	}
+
+	pipeline := PipelineConfig{
+		Groups: atc.GroupConfigs{
+			{Name: "all", Jobs: []string{"build"}},
+		},
+		Jobs: atc.JobConfigs{
+			{
+				Name:   "build",
+				Serial: true,
+				Plan: atc.PlanSequence{
+					{Get: "repo", Trigger: true},
+					{
+						Task:           "build",
+						TaskConfigPath: "ci/tasks/build.yml",
+						Params:         atc.Params{"COUNT": 3},
+						Failure: &atc.PlanConfig{
+							Put:    "notify",
+							Params: atc.Params{"text": "build failed"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	project := ProjectConfig{
+		Name: "ci",
+		Plan: []map[string]string{
+			{"set_pipeline": "main"},
+		},
+	}
+
+	return []templateSample{
+		{
+			File:     GeneratedFile{Path: "resources/repo.yml", Kind: "resource", Name: "repo"},
+			Template: templates.resourceTemplate(resource.Type),
+			Value:    resource,
+		},
+		{
+			File:     GeneratedFile{Path: "resource-types/slack-notification.yml", Kind: "resource-type", Name: "slack-notification"},
+			Template: templates.resourceTemplate(resourceType.Type),
+			Value:    resourceType,
+		},
+		{
+			File:     GeneratedFile{Path: "tasks/build.yml", Kind: "task", Name: "build"},
+			Template: "task.tmpl",
+			Value:    task,
+		},
+		{
+			File:     GeneratedFile{Path: "pipelines/main.yml", Kind: "pipeline", Name: "main"},
+			Template: "pipeline.tmpl",
+			Value:    pipeline,
+		},
+		{
+			File:     GeneratedFile{Path: "project.yml", Kind: "project", Name: "ci"},
+			Template: "project.tmpl",
+			Value:    project,
+		},
+	}
+}
+
+// ValidateTemplates renders each template against a sample value, reporting
+// whether each one passes the equivalence check.
+func ValidateTemplates(w io.Writer, opts Options) error {
+	c, err := newConverter(opts)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, sample := range templateSamples(c.Templates) {
+		_, err := c.prettyPrint(sample.File, sample.Template, sample.Value)
+		if err != nil {
+			failed++
+			fmt.Fprintf(w, "FAIL %s (%s)\n\n%s\n\n", sample.Template, sample.File.Kind, err)
+			continue
+		}
+
+		fmt.Fprintf(w, "ok   %s (%s)\n", sample.Template, sample.File.Kind)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d templates failed validation", failed)
+	}
+
+	return nil
+}
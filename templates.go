@@ -0,0 +1,211 @@
+package pipe2proj
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+// builtinTemplatesFS holds the templates used when no others are given.
+//
+//go:embed tmpl/*.tmpl
+var builtinTemplatesFS embed.FS
+
+// builtinTemplates can't be overridden for a particular resource type, as
+// they're already used for other kinds of files.
+var builtinTemplates = map[string]bool{
+	"pipeline.tmpl": true,
+	"project.tmpl":  true,
+	"resource.tmpl": true,
+	"task.tmpl":     true,
+}
+
+// Templates pretty-print the generated files.
+type Templates struct {
+	tmpl  *template.Template
+	funcs template.FuncMap
+
+	// the names of templates added from a dir rather than built in
+	custom map[string]bool
+}
+
+// NewTemplates parses the built-in templates. Unless allowMissingKeys is set,
+// rendering a missing map key fails rather than rendering '<no value>'.
+func NewTemplates(allowMissingKeys bool) (*Templates, error) {
+	funcs := template.FuncMap{
+		"yaml": func(indent int, x interface{}) (string, error) {
+			payload, err := yaml.Marshal(x)
+			if err != nil {
+				return "", err
+			}
+
+			trimmed := strings.TrimSuffix(string(payload), "\n")
+
+			var indented string
+			for i, line := range strings.Split(trimmed, "\n") {
+				if i > 0 {
+					indented += "\n"
+
+					// don't leave trailing whitespace on blank lines in block
+					// scalars
+					if line != "" {
+						indented += strings.Repeat("  ", indent)
+					}
+				}
+
+				indented += line
+			}
+
+			return indented, nil
+		},
+		"indent": func(prefix string, x interface{}) (string, error) {
+			payload, err := yaml.Marshal(x)
+			if err != nil {
+				return "", err
+			}
+
+			lines := strings.Split(strings.TrimSuffix(string(payload), "\n"), "\n")
+			for i, line := range lines {
+				if line != "" {
+					lines[i] = prefix + line
+				}
+			}
+
+			return strings.Join(lines, "\n"), nil
+		},
+		"quote": func(x interface{}) string {
+			// Go's escape sequences are a subset of those allowed in YAML's
+			// double-quoted scalars.
+			return strconv.Quote(fmt.Sprint(x))
+		},
+		"hasKey": func(m interface{}, key string) bool {
+			val := reflect.ValueOf(m)
+			if val.Kind() != reflect.Map || val.Type().Key().Kind() != reflect.String {
+				return false
+			}
+
+			return val.MapIndex(reflect.ValueOf(key).Convert(val.Type().Key())).IsValid()
+		},
+		"default": func(def interface{}, x interface{}) interface{} {
+			if x == nil {
+				return def
+			}
+
+			val := reflect.ValueOf(x)
+			switch val.Kind() {
+			case reflect.Map, reflect.Slice, reflect.Array, reflect.String:
+				if val.Len() == 0 {
+					return def
+				}
+			default:
+				if reflect.DeepEqual(x, reflect.Zero(val.Type()).Interface()) {
+					return def
+				}
+			}
+
+			return x
+		},
+	}
+
+	tmpl := template.New("root").Funcs(funcs)
+
+	if !allowMissingKeys {
+		tmpl.Option("missingkey=error")
+	}
+
+	_, err := tmpl.ParseFS(builtinTemplatesFS, "tmpl/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Templates{
+		tmpl:   tmpl,
+		funcs:  funcs,
+		custom: map[string]bool{},
+	}, nil
+}
+
+// AddDir parses every template under the dir, naming each by its path
+// relative to the dir and overriding any template already of that name.
+// Templates defined with 'define' are available by name across files, but may
+// only be defined once per dir.
+func (templates *Templates) AddDir(dir fs.FS) error {
+	files, err := TemplateFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	definedBy := map[string]string{}
+	for _, file := range files {
+		content, err := fs.ReadFile(dir, file)
+		if err != nil {
+			return err
+		}
+
+		parsed, err := template.New(file).Funcs(templates.funcs).Parse(string(content))
+		if err != nil {
+			return err
+		}
+
+		for _, tmpl := range parsed.Templates() {
+			name := tmpl.Name()
+
+			if other, found := definedBy[name]; found {
+				return fmt.Errorf("template '%s' is defined by both %s and %s", name, other, file)
+			}
+
+			definedBy[name] = file
+
+			_, err := templates.tmpl.AddParseTree(name, tmpl.Tree)
+			if err != nil {
+				return err
+			}
+		}
+
+		templates.custom[file] = true
+	}
+
+	return nil
+}
+
+// TemplateFiles returns the slash-separated path of every template under the
+// dir.
+func TemplateFiles(dir fs.FS) ([]string, error) {
+	var files []string
+	err := fs.WalkDir(dir, ".", func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() || path.Ext(file) != ".tmpl" {
+			return nil
+		}
+
+		files = append(files, file)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// resourceTemplate returns the template to render a resource or resource type
+// of the given type with: '<type>.tmpl' if there is one, or 'resource.tmpl'.
+func (templates *Templates) resourceTemplate(resourceType string) string {
+	name := resourceType + ".tmpl"
+	if !builtinTemplates[name] && templates != nil && templates.tmpl.Lookup(name) != nil {
+		return name
+	}
+
+	return "resource.tmpl"
+}
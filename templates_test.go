@@ -0,0 +1,139 @@
+package pipe2proj
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TestBuiltinTemplatesCorpus converts each pipeline in testdata/corpus with
+// the built-in templates and without any, checking that every file decodes to
+// the same value either way. Rendering already checks this for each file, so
+// this mostly catches the templates failing to render a field at all.
+func TestBuiltinTemplatesCorpus(t *testing.T) {
+	pipelines, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts := map[string]fs.FS{
+		"tasks": os.DirFS(filepath.Join("testdata", "corpus", "tasks")),
+	}
+
+	for _, pipeline := range pipelines {
+		pipeline := pipeline
+
+		t.Run(filepath.Base(pipeline), func(t *testing.T) {
+			payload, err := os.ReadFile(pipeline)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opts := Options{
+				ProjectName:   "ci",
+				PipelineName:  "main",
+				Config:        payload,
+				TaskArtifacts: artifacts,
+			}
+
+			pretty, err := Convert(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opts.NoTemplates = true
+
+			raw, err := Convert(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(pretty.Files) != len(raw.Files) {
+				t.Fatalf("expected %d files, got %d", len(raw.Files), len(pretty.Files))
+			}
+
+			for i, file := range pretty.Files {
+				if file.Path != raw.Files[i].Path {
+					t.Fatalf("expected %s, got %s", raw.Files[i].Path, file.Path)
+				}
+
+				if file.Kind == "script" {
+					continue
+				}
+
+				if string(file.Payload) == string(raw.Files[i].Payload) {
+					t.Errorf("%s: expected the built-in templates to pretty-print it", file.Path)
+				}
+
+				var x, y interface{}
+				err := yaml.Unmarshal(file.Payload, &x)
+				if err != nil {
+					t.Fatalf("%s: %s", file.Path, err)
+				}
+
+				err = yaml.Unmarshal(raw.Files[i].Payload, &y)
+				if err != nil {
+					t.Fatalf("%s: %s", file.Path, err)
+				}
+
+				if !reflect.DeepEqual(x, y) {
+					t.Errorf("%s: not equivalent:\n\n%s\n\nmarshalled:\n\n%s", file.Path, file.Payload, raw.Files[i].Payload)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkConvertTemplates(b *testing.B) {
+	payload := []byte(`
+resources:
+- name: repo
+  type: git
+  source: {uri: https://example.com/repo.git}
+
+jobs:
+- name: unit
+  plan:
+  - get: repo
+    trigger: true
+  - task: unit
+    file: repo/ci/unit.yml
+`)
+
+	opts := Options{
+		ProjectName:   "ci",
+		PipelineName:  "main",
+		Config:        payload,
+		TaskArtifacts: map[string]fs.FS{"repo": ciArtifact},
+	}
+
+	b.Run("parsed each time", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := Convert(opts)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("reused", func(b *testing.B) {
+		templates, err := NewTemplates(false)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		opts := opts
+		opts.Templates = templates
+
+		for i := 0; i < b.N; i++ {
+			_, err := Convert(opts)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
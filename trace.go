@@ -0,0 +1,76 @@
+package pipe2proj
+
+import (
+	"sort"
+	"time"
+)
+
+// Trace times the phases of a conversion, and the files which took longest
+// to render, to show where the time goes. It's always recorded, since timing
+// a few phases costs next to nothing.
+type Trace struct {
+	Phases []TracePhase `json:"phases"`
+
+	// files whose rendering took at least traceFileThreshold
+	Files []TraceFile `json:"files,omitempty"`
+}
+
+// TracePhase is the time taken by a phase of the conversion, e.g.
+// 'main: resources'.
+type TracePhase struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// TraceFile is the time taken to render a file through its template and to
+// check that the result is equivalent to what was rendered.
+type TraceFile struct {
+	Path   string        `json:"path"`
+	Render time.Duration `json:"render_ns"`
+	Check  time.Duration `json:"check_ns"`
+}
+
+// quickest file rendering worth tracing
+const traceFileThreshold = 5 * time.Millisecond
+
+// phase starts timing the named phase, returning a func which stops.
+func (trace *Trace) phase(name string) func() {
+	start := time.Now()
+
+	return func() {
+		trace.Phases = append(trace.Phases, TracePhase{
+			Name:     name,
+			Duration: time.Since(start),
+		})
+	}
+}
+
+// addFile records how long the file took to render, if it took long enough
+// to be worth knowing about.
+func (trace *Trace) addFile(path string, render time.Duration, check time.Duration) {
+	if render+check < traceFileThreshold {
+		return
+	}
+
+	trace.Files = append(trace.Files, TraceFile{
+		Path:   path,
+		Render: render,
+		Check:  check,
+	})
+}
+
+// Slowest returns the phases and files sorted by the time they took, longest
+// first.
+func (trace Trace) Slowest() ([]TracePhase, []TraceFile) {
+	phases := append([]TracePhase{}, trace.Phases...)
+	sort.SliceStable(phases, func(i, j int) bool {
+		return phases[i].Duration > phases[j].Duration
+	})
+
+	files := append([]TraceFile{}, trace.Files...)
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Render+files[i].Check > files[j].Render+files[j].Check
+	})
+
+	return phases, files
+}
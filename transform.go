@@ -0,0 +1,83 @@
+package pipe2proj
+
+import (
+	"github.com/concourse/concourse/atc"
+)
+
+// StepTransform modifies a step in a job's plan during conversion.
+type StepTransform func(atc.PlanConfig) atc.PlanConfig
+
+// stepTransforms returns the transforms to run over every step: the built-in
+// transforms enabled by flags, followed by any given in the options.
+func (c *converter) stepTransforms() []StepTransform {
+	var transforms []StepTransform
+
+	if c.DefaultTaskTimeout != "" {
+		transforms = append(transforms, defaultTaskTimeout(c.DefaultTaskTimeout))
+	}
+
+	if c.DefaultStepTimeout != "" {
+		transforms = append(transforms, defaultStepTimeout(c.DefaultStepTimeout))
+	}
+
+	if len(c.TaskTags) > 0 {
+		transforms = append(transforms, addTaskTags(c.TaskTags))
+	}
+
+	return append(transforms, c.StepTransforms...)
+}
+
+// defaultTaskTimeout sets a timeout on tasks which don't have one.
+func defaultTaskTimeout(timeout string) StepTransform {
+	return func(step atc.PlanConfig) atc.PlanConfig {
+		if step.Task != "" && step.Timeout == "" {
+			step.Timeout = timeout
+		}
+
+		return step
+	}
+}
+
+// defaultStepTimeout sets a timeout on get, put, and task steps which don't
+// have one.
+func defaultStepTimeout(timeout string) StepTransform {
+	return func(step atc.PlanConfig) atc.PlanConfig {
+		if step.Get == "" && step.Put == "" && step.Task == "" {
+			return step
+		}
+
+		if step.Timeout == "" {
+			step.Timeout = timeout
+		}
+
+		return step
+	}
+}
+
+// addTaskTags adds tags to every task, skipping those it already has.
+func addTaskTags(tags []string) StepTransform {
+	return func(step atc.PlanConfig) atc.PlanConfig {
+		if step.Task == "" {
+			return step
+		}
+
+		newTags := append(atc.Tags{}, step.Tags...)
+		for _, tag := range tags {
+			var found bool
+			for _, existing := range newTags {
+				if existing == tag {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				newTags = append(newTags, tag)
+			}
+		}
+
+		step.Tags = newTags
+
+		return step
+	}
+}
@@ -0,0 +1,67 @@
+package pipe2proj
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+func TestDefaultTimeouts(t *testing.T) {
+	for _, test := range []struct {
+		title    string
+		task     string
+		step     string
+		timeouts []string
+		invalid  bool
+	}{
+		{
+			title:    "default task timeout",
+			task:     "30m",
+			timeouts: []string{"", "30m", "1h", "5m"},
+		},
+		{
+			title:    "default step timeout",
+			step:     "30m",
+			timeouts: []string{"30m", "30m", "1h", "5m"},
+		},
+		{
+			title:   "both",
+			task:    "30m",
+			step:    "10m",
+			invalid: true,
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			result, err := Convert(Options{
+				ProjectName:        "ci",
+				PipelineName:       "main",
+				Config:             readFixture(t, "timeouts.yml"),
+				TaskArtifacts:      map[string]fs.FS{"repo": ciArtifact},
+				DefaultTaskTimeout: test.task,
+				DefaultStepTimeout: test.step,
+			})
+			if test.invalid {
+				var invalid ValidationError
+				if !errors.As(err, &invalid) {
+					t.Fatalf("expected a validation error, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var timeouts []string
+			for _, step := range convertedPipeline(t, result, "main").Jobs[0].Plan {
+				timeouts = append(timeouts, step.Timeout)
+			}
+
+			if !reflect.DeepEqual(timeouts, test.timeouts) {
+				t.Errorf("expected timeouts %q, got %q", test.timeouts, timeouts)
+			}
+		})
+	}
+}
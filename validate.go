@@ -0,0 +1,88 @@
+package pipe2proj
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validateNames checks for resources, resource types, and jobs sharing a
+// name, which would otherwise clobber each other's files.
+func validateNames(config PipelineConfig) error {
+	var errs []string
+
+	var resourceNames []string
+	for _, res := range config.Resources {
+		resourceNames = append(resourceNames, res.Name)
+	}
+
+	var resourceTypeNames []string
+	for _, res := range config.ResourceTypes {
+		resourceTypeNames = append(resourceTypeNames, res.Name)
+	}
+
+	var jobNames []string
+	for _, job := range config.Jobs {
+		jobNames = append(jobNames, job.Name)
+	}
+
+	for _, name := range duplicates(resourceNames) {
+		errs = append(errs, fmt.Sprintf("duplicate resource name '%s'", name))
+	}
+
+	for _, name := range duplicates(resourceTypeNames) {
+		errs = append(errs, fmt.Sprintf("duplicate resource type name '%s'", name))
+	}
+
+	for _, name := range duplicates(jobNames) {
+		errs = append(errs, fmt.Sprintf("duplicate job name '%s'", name))
+	}
+
+	if len(errs) > 0 {
+		return invalidf("invalid pipeline config:\n\n%s", strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
+
+// validateGroups checks that every job and resource named by a group is in
+// the converted pipeline, as Concourse would when setting it, so that e.g. a
+// reference left behind by editing the pipeline by hand is caught now.
+func validateGroups(config PipelineConfig) error {
+	var errs []string
+	for _, group := range config.Groups {
+		for _, name := range group.Jobs {
+			if _, found := config.Jobs.Lookup(name); !found {
+				errs = append(errs, fmt.Sprintf("group '%s' has unknown job '%s'", group.Name, name))
+			}
+		}
+
+		for _, name := range group.Resources {
+			if _, found := config.Resources.Lookup(name); !found {
+				errs = append(errs, fmt.Sprintf("group '%s' has unknown resource '%s'", group.Name, name))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return invalidf("invalid groups in converted pipeline:\n\n%s", strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
+
+// duplicates returns each name that appears more than once, in order of
+// first appearance.
+func duplicates(names []string) []string {
+	var dupes []string
+
+	counts := map[string]int{}
+	for _, name := range names {
+		counts[name]++
+
+		if counts[name] == 2 {
+			dupes = append(dupes, name)
+		}
+	}
+
+	return dupes
+}
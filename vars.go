@@ -0,0 +1,38 @@
+package pipe2proj
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/concourse/concourse/atc"
+)
+
+var varRegexp = regexp.MustCompile(`\(\(([^()]+)\)\)`)
+
+// interpolateVars replaces each ((var)) in the string with its value,
+// returning the names of any vars which couldn't be resolved. Fields of a var
+// may be referenced with dots, e.g. ((var.field)).
+func interpolateVars(str string, vars atc.Source) (string, []string) {
+	var unresolved []string
+
+	interpolated := varRegexp.ReplaceAllStringFunc(str, func(match string) string {
+		name := strings.TrimSpace(varRegexp.FindStringSubmatch(match)[1])
+
+		val, found := sourceValue(vars, strings.Split(name, "."))
+		if !found {
+			unresolved = append(unresolved, name)
+			return match
+		}
+
+		switch val.(type) {
+		case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+			unresolved = append(unresolved, name)
+			return match
+		}
+
+		return fmt.Sprint(val)
+	})
+
+	return interpolated, unresolved
+}
@@ -0,0 +1,101 @@
+package pipe2proj
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+	"gopkg.in/yaml.v2"
+)
+
+var typeErrorLineRegexp = regexp.MustCompile(`^line (\d+): `)
+
+// varField is a value which is entirely a ((var)) reference.
+type varField struct {
+	Path  string
+	Value string
+}
+
+// explainVarTypeError explains an unmarshalling error caused by a ((var))
+// standing in for a value which can't be a string, e.g.
+// 'optional: ((optional))', naming the field each such var is in. Vars in
+// string fields are preserved as-is, so other errors are returned unchanged.
+func explainVarTypeError(payload []byte, err error) error {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+
+	fields := varFields(payload)
+
+	var explained []string
+	for _, msg := range typeErr.Errors {
+		match := typeErrorLineRegexp.FindStringSubmatch(msg)
+		if match == nil {
+			continue
+		}
+
+		line, _ := strconv.Atoi(match[1])
+		for _, field := range fields[line] {
+			explained = append(explained, fmt.Sprintf("%s (line %d): %s", field.Path, line, field.Value))
+		}
+	}
+
+	if len(explained) == 0 {
+		return err
+	}
+
+	return fmt.Errorf("vars can only be preserved in string fields, but are used in:\n  %s", strings.Join(explained, "\n  "))
+}
+
+// varFields finds every scalar in the document which is entirely a var
+// reference, keyed by line.
+func varFields(payload []byte) map[int][]varField {
+	var doc yamlv3.Node
+	err := yamlv3.Unmarshal(payload, &doc)
+	if err != nil {
+		return nil
+	}
+
+	fields := map[int][]varField{}
+
+	var walk func(path string, node *yamlv3.Node)
+	walk = func(path string, node *yamlv3.Node) {
+		switch node.Kind {
+		case yamlv3.DocumentNode:
+			for _, child := range node.Content {
+				walk(path, child)
+			}
+
+		case yamlv3.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key := node.Content[i].Value
+				if path != "" {
+					key = path + "." + key
+				}
+
+				walk(key, node.Content[i+1])
+			}
+
+		case yamlv3.SequenceNode:
+			for i, child := range node.Content {
+				walk(fmt.Sprintf("%s[%d]", path, i), child)
+			}
+
+		case yamlv3.ScalarNode:
+			if varRegexp.FindString(node.Value) == node.Value {
+				fields[node.Line] = append(fields[node.Line], varField{
+					Path:  path,
+					Value: node.Value,
+				})
+			}
+		}
+	}
+
+	walk("", &doc)
+
+	return fields
+}
@@ -0,0 +1,258 @@
+package pipe2proj
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/concourse/concourse/atc"
+	"gopkg.in/yaml.v2"
+)
+
+// StepPath is the location of a step within a job or plan, e.g.
+// 'plan[2].in_parallel[0].on_failure'.
+type StepPath string
+
+func (path StepPath) field(key string) StepPath {
+	if path == "" {
+		return StepPath(key)
+	}
+
+	return path + "." + StepPath(key)
+}
+
+func (path StepPath) index(i int) StepPath {
+	return path + "[" + StepPath(strconv.Itoa(i)) + "]"
+}
+
+// WalkFunc is called with each step in a plan, returning the step to replace
+// it with.
+type WalkFunc func(path StepPath, step atc.PlanConfig) (atc.PlanConfig, error)
+
+// VisitFunc is called with each step in a plan.
+type VisitFunc func(path StepPath, step atc.PlanConfig) error
+
+// the keys of the hooks a step or job can have, in the order they're walked
+var hookKeys = [...]string{"on_abort", "on_error", "on_success", "on_failure", "ensure"}
+
+// The hooks are read out as arrays and set back afterwards, rather than
+// referred to by pointer, so that walking doesn't move every step to the
+// heap.
+
+func planHooks(plan atc.PlanConfig) [len(hookKeys)]*atc.PlanConfig {
+	return [...]*atc.PlanConfig{plan.Abort, plan.Error, plan.Success, plan.Failure, plan.Ensure}
+}
+
+func setPlanHooks(plan *atc.PlanConfig, hooks [len(hookKeys)]*atc.PlanConfig) {
+	plan.Abort, plan.Error, plan.Success, plan.Failure, plan.Ensure = hooks[0], hooks[1], hooks[2], hooks[3], hooks[4]
+}
+
+func jobHooks(job atc.JobConfig) [len(hookKeys)]*atc.PlanConfig {
+	return [...]*atc.PlanConfig{job.Abort, job.Error, job.Success, job.Failure, job.Ensure}
+}
+
+func setJobHooks(job *atc.JobConfig, hooks [len(hookKeys)]*atc.PlanConfig) {
+	job.Abort, job.Error, job.Success, job.Failure, job.Ensure = hooks[0], hooks[1], hooks[2], hooks[3], hooks[4]
+}
+
+// walkHooks walks each of the hooks which is set.
+func walkHooks(path StepPath, hooks [len(hookKeys)]*atc.PlanConfig, fn WalkFunc) ([len(hookKeys)]*atc.PlanConfig, error) {
+	for i, hook := range hooks {
+		if hook == nil {
+			continue
+		}
+
+		walked, err := walkPlan(path.field(hookKeys[i]), *hook, fn)
+		if err != nil {
+			return hooks, err
+		}
+
+		hooks[i] = &walked
+	}
+
+	return hooks, nil
+}
+
+func visitHooks(path StepPath, hooks [len(hookKeys)]*atc.PlanConfig, fn VisitFunc) error {
+	for i, hook := range hooks {
+		if hook == nil {
+			continue
+		}
+
+		err := visitPlan(path.field(hookKeys[i]), *hook, fn)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WalkJob calls fn for every step in the job's plan and hooks, replacing each
+// step with the result. Paths start at the job, e.g. 'plan[0]' or 'ensure'.
+//
+// The plan is walked as the sequence it is rather than being wrapped in a do
+// step and unwrapped afterwards, so a plan which is a single do step comes
+// back as exactly that, without any added nesting.
+func WalkJob(job atc.JobConfig, fn WalkFunc) (atc.JobConfig, error) {
+	plan, err := walkSteps("plan", job.Plan, fn)
+	if err != nil {
+		return atc.JobConfig{}, err
+	}
+
+	job.Plan = plan
+
+	hooks, err := walkHooks("", jobHooks(job), fn)
+	if err != nil {
+		return atc.JobConfig{}, err
+	}
+
+	setJobHooks(&job, hooks)
+
+	return job, nil
+}
+
+// WalkPlan calls fn for every step in the plan, including hooks and the steps
+// within do, try, aggregate, and in_parallel, replacing each step with the
+// result. Steps are walked depth-first, so fn sees a step's hooks and nested
+// steps already replaced.
+func WalkPlan(plan atc.PlanConfig, fn WalkFunc) (atc.PlanConfig, error) {
+	return walkPlan("", plan, fn)
+}
+
+func walkPlan(path StepPath, plan atc.PlanConfig, fn WalkFunc) (atc.PlanConfig, error) {
+	hooks, err := walkHooks(path, planHooks(plan), fn)
+	if err != nil {
+		return atc.PlanConfig{}, err
+	}
+
+	setPlanHooks(&plan, hooks)
+
+	switch {
+	case plan.Try != nil:
+		walked, err := walkPlan(path.field("try"), *plan.Try, fn)
+		if err != nil {
+			return atc.PlanConfig{}, err
+		}
+
+		plan.Try = &walked
+
+	case plan.Do != nil:
+		steps, err := walkSteps(path.field("do"), *plan.Do, fn)
+		if err != nil {
+			return atc.PlanConfig{}, err
+		}
+
+		plan.Do = &steps
+
+	case plan.Aggregate != nil:
+		steps, err := walkSteps(path.field("aggregate"), *plan.Aggregate, fn)
+		if err != nil {
+			return atc.PlanConfig{}, err
+		}
+
+		plan.Aggregate = &steps
+
+	case plan.InParallel != nil:
+		steps, err := walkSteps(path.field("in_parallel"), plan.InParallel.Steps, fn)
+		if err != nil {
+			return atc.PlanConfig{}, err
+		}
+
+		inParallel := *plan.InParallel
+		inParallel.Steps = steps
+		plan.InParallel = &inParallel
+
+	case plan.Get != "", plan.Put != "", plan.Task != "":
+
+	default:
+		return atc.PlanConfig{}, unknownStep(path, plan)
+	}
+
+	return fn(path, plan)
+}
+
+func walkSteps(path StepPath, steps atc.PlanSequence, fn WalkFunc) (atc.PlanSequence, error) {
+	if len(steps) == 0 {
+		// e.g. 'do: []' stays as it was rather than becoming nil
+		return steps, nil
+	}
+
+	walked := make(atc.PlanSequence, len(steps))
+	for i := range steps {
+		var err error
+		walked[i], err = walkPlan(path.index(i), steps[i], fn)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return walked, nil
+}
+
+// VisitJob calls fn for every step in the job's plan and hooks, in the same
+// order as WalkJob, without rebuilding the job.
+func VisitJob(job atc.JobConfig, fn VisitFunc) error {
+	err := visitSteps("plan", job.Plan, fn)
+	if err != nil {
+		return err
+	}
+
+	return visitHooks("", jobHooks(job), fn)
+}
+
+// VisitPlan calls fn for every step in the plan, in the same order as
+// WalkPlan, without rebuilding the plan.
+func VisitPlan(plan atc.PlanConfig, fn VisitFunc) error {
+	return visitPlan("", plan, fn)
+}
+
+func visitPlan(path StepPath, plan atc.PlanConfig, fn VisitFunc) error {
+	err := visitHooks(path, planHooks(plan), fn)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case plan.Try != nil:
+		err = visitPlan(path.field("try"), *plan.Try, fn)
+	case plan.Do != nil:
+		err = visitSteps(path.field("do"), *plan.Do, fn)
+	case plan.Aggregate != nil:
+		err = visitSteps(path.field("aggregate"), *plan.Aggregate, fn)
+	case plan.InParallel != nil:
+		err = visitSteps(path.field("in_parallel"), plan.InParallel.Steps, fn)
+	case plan.Get != "", plan.Put != "", plan.Task != "":
+	default:
+		err = unknownStep(path, plan)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return fn(path, plan)
+}
+
+func visitSteps(path StepPath, steps atc.PlanSequence, fn VisitFunc) error {
+	for i := range steps {
+		err := visitPlan(path.index(i), steps[i], fn)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unknownStep(path StepPath, plan atc.PlanConfig) error {
+	prettyStep, err := yaml.Marshal(plan)
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fmt.Errorf("unknown step type:\n\n%s", prettyStep)
+	}
+
+	return fmt.Errorf("%s: unknown step type:\n\n%s", path, prettyStep)
+}
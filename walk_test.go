@@ -0,0 +1,461 @@
+package pipe2proj
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/concourse/concourse/atc"
+	"gopkg.in/yaml.v2"
+)
+
+// stepDesc describes a step by its kind and name, e.g. 'task unit'.
+func stepDesc(step atc.PlanConfig) string {
+	switch {
+	case step.Get != "":
+		return "get " + step.Get
+	case step.Put != "":
+		return "put " + step.Put
+	case step.Task != "":
+		return "task " + step.Task
+	case step.Try != nil:
+		return "try"
+	case step.Do != nil:
+		return "do"
+	case step.Aggregate != nil:
+		return "aggregate"
+	case step.InParallel != nil:
+		return "in_parallel"
+	default:
+		return "unknown"
+	}
+}
+
+func parseJob(t testing.TB, payload string) atc.JobConfig {
+	t.Helper()
+
+	var job atc.JobConfig
+	err := yaml.Unmarshal([]byte(payload), &job)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return job
+}
+
+func parsePlan(t testing.TB, payload string) atc.PlanConfig {
+	t.Helper()
+
+	var plan atc.PlanConfig
+	err := yaml.Unmarshal([]byte(payload), &plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return plan
+}
+
+const walkedJob = `
+name: unit
+plan:
+- get: repo
+  on_failure: {task: notify-get}
+- in_parallel:
+    limit: 1
+    steps:
+    - task: a
+    - try: {task: b}
+- aggregate:
+  - put: out
+    ensure: {task: cleanup}
+    on_abort: {task: aborted}
+- do:
+  - task: c
+  - do: []
+on_success: {task: done}
+ensure:
+  do:
+  - task: e
+`
+
+// the steps of walkedJob in the order they're walked
+var walkedJobSteps = []string{
+	"plan[0].on_failure: task notify-get",
+	"plan[0]: get repo",
+	"plan[1].in_parallel[0]: task a",
+	"plan[1].in_parallel[1].try: task b",
+	"plan[1].in_parallel[1]: try",
+	"plan[1]: in_parallel",
+	"plan[2].aggregate[0].on_abort: task aborted",
+	"plan[2].aggregate[0].ensure: task cleanup",
+	"plan[2].aggregate[0]: put out",
+	"plan[2]: aggregate",
+	"plan[3].do[0]: task c",
+	"plan[3].do[1]: do",
+	"plan[3]: do",
+	"on_success: task done",
+	"ensure.do[0]: task e",
+	"ensure: do",
+}
+
+// walkedJob with each task's name suffixed, as by renameTasks
+const renamedJob = `
+name: unit
+plan:
+- get: repo
+  on_failure: {task: notify-get-walked}
+- in_parallel:
+    limit: 1
+    steps:
+    - task: a-walked
+    - try: {task: b-walked}
+- aggregate:
+  - put: out
+    ensure: {task: cleanup-walked}
+    on_abort: {task: aborted-walked}
+- do:
+  - task: c-walked
+  - do: []
+on_success: {task: done-walked}
+ensure:
+  do:
+  - task: e-walked
+`
+
+const walkedPlan = `
+try:
+  do:
+  - get: repo
+  - task: unit
+    ensure: {put: repo}
+on_failure:
+  in_parallel: [{task: alert}]
+`
+
+// the steps of walkedPlan in the order they're walked
+var walkedPlanSteps = []string{
+	"on_failure.in_parallel[0]: task alert",
+	"on_failure: in_parallel",
+	"try.do[0]: get repo",
+	"try.do[1].ensure: put repo",
+	"try.do[1]: task unit",
+	"try: do",
+	": try",
+}
+
+const renamedPlan = `
+try:
+  do:
+  - get: repo
+  - task: unit-walked
+    ensure: {put: repo}
+on_failure:
+  in_parallel: [{task: alert-walked}]
+`
+
+// renameTasks suffixes the name of each task, checking that nested steps have
+// already been walked.
+func renameTasks(visited *[]string) WalkFunc {
+	return func(path StepPath, step atc.PlanConfig) (atc.PlanConfig, error) {
+		*visited = append(*visited, fmt.Sprintf("%s: %s", path, stepDesc(step)))
+
+		if step.Try != nil && step.Try.Task != "" && !strings.HasSuffix(step.Try.Task, "-walked") {
+			return step, fmt.Errorf("%s: nested step not walked first", path)
+		}
+
+		if step.Task != "" {
+			step.Task += "-walked"
+		}
+
+		return step, nil
+	}
+}
+
+func recordSteps(visited *[]string) VisitFunc {
+	return func(path StepPath, step atc.PlanConfig) error {
+		*visited = append(*visited, fmt.Sprintf("%s: %s", path, stepDesc(step)))
+		return nil
+	}
+}
+
+func TestWalk(t *testing.T) {
+	for _, test := range []struct {
+		title    string
+		walk     func(t *testing.T, fn WalkFunc) (interface{}, error)
+		visit    func(t *testing.T, fn VisitFunc) error
+		steps    []string
+		expected func(t *testing.T) interface{}
+	}{
+		{
+			title: "job",
+			walk: func(t *testing.T, fn WalkFunc) (interface{}, error) {
+				return WalkJob(parseJob(t, walkedJob), fn)
+			},
+			visit: func(t *testing.T, fn VisitFunc) error {
+				return VisitJob(parseJob(t, walkedJob), fn)
+			},
+			steps: walkedJobSteps,
+			expected: func(t *testing.T) interface{} {
+				return parseJob(t, renamedJob)
+			},
+		},
+		{
+			title: "plan",
+			walk: func(t *testing.T, fn WalkFunc) (interface{}, error) {
+				return WalkPlan(parsePlan(t, walkedPlan), fn)
+			},
+			visit: func(t *testing.T, fn VisitFunc) error {
+				return VisitPlan(parsePlan(t, walkedPlan), fn)
+			},
+			steps: walkedPlanSteps,
+			expected: func(t *testing.T) interface{} {
+				return parsePlan(t, renamedPlan)
+			},
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			var walked []string
+			result, err := test.walk(t, renameTasks(&walked))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(walked, test.steps) {
+				t.Errorf("walked:\n\n%s\n\nexpected:\n\n%s", strings.Join(walked, "\n"), strings.Join(test.steps, "\n"))
+			}
+
+			expected := test.expected(t)
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("expected %#v\n\ngot %#v", expected, result)
+			}
+
+			var visited []string
+			err = test.visit(t, recordSteps(&visited))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(visited, test.steps) {
+				t.Errorf("visited:\n\n%s\n\nexpected:\n\n%s", strings.Join(visited, "\n"), strings.Join(test.steps, "\n"))
+			}
+		})
+	}
+}
+
+func TestWalkLeavesOriginalUnchanged(t *testing.T) {
+	job := parseJob(t, walkedJob)
+
+	var walked []string
+	_, err := WalkJob(job, renameTasks(&walked))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(job, parseJob(t, walkedJob)) {
+		t.Errorf("expected the walked job to be left as it was, got %#v", job)
+	}
+}
+
+func TestWalkEmptySequences(t *testing.T) {
+	job := parseJob(t, walkedJob)
+
+	walked, err := WalkJob(job, func(_ StepPath, step atc.PlanConfig) (atc.PlanConfig, error) {
+		return step, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	empty := walked.Plan[3].Do
+	if empty == nil || (*empty)[1].Do == nil || *(*empty)[1].Do == nil || len(*(*empty)[1].Do) != 0 {
+		t.Errorf("expected 'do: []' to stay an empty sequence, got %#v", (*empty)[1].Do)
+	}
+
+	if !reflect.DeepEqual(walked, job) {
+		t.Errorf("expected an identity walk to change nothing:\n\n%#v\n\ngot %#v", job, walked)
+	}
+}
+
+func TestWalkJobSingleDo(t *testing.T) {
+	config, _, err := parsePipeline(readFixture(t, "single-do.yml"), "refuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, job := range config.Jobs {
+		t.Run(job.Name, func(t *testing.T) {
+			var paths []StepPath
+			walked, err := WalkJob(job, func(path StepPath, step atc.PlanConfig) (atc.PlanConfig, error) {
+				paths = append(paths, path)
+				return step, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(walked, job) {
+				t.Errorf("expected the plan to be left as it was:\n\n%#v\n\ngot %#v", job.Plan, walked.Plan)
+			}
+
+			// the plan itself is never walked as a step
+			if last := paths[len(paths)-1]; last != "plan[0]" {
+				t.Errorf("expected the single do step to be walked last, got %s", last)
+			}
+		})
+	}
+}
+
+func TestWalkErrors(t *testing.T) {
+	errStop := errors.New("stop")
+
+	for _, test := range []struct {
+		title   string
+		run     func(t *testing.T) error
+		message string
+		is      error
+	}{
+		{
+			title: "unknown step in a job",
+			run: func(t *testing.T) error {
+				_, err := WalkJob(parseJob(t, "{name: unit, plan: [{do: [{attempts: 2}]}]}"), func(_ StepPath, step atc.PlanConfig) (atc.PlanConfig, error) {
+					return step, nil
+				})
+				return err
+			},
+			message: "plan[0].do[0]: unknown step type:\n\nattempts: 2\n",
+		},
+		{
+			title: "unknown step visiting a job's hook",
+			run: func(t *testing.T) error {
+				return VisitJob(parseJob(t, "{name: unit, ensure: {timeout: 1h}}"), func(StepPath, atc.PlanConfig) error {
+					return nil
+				})
+			},
+			message: "ensure: unknown step type:\n\ntimeout: 1h\n",
+		},
+		{
+			title: "unknown step at the root of a plan",
+			run: func(t *testing.T) error {
+				_, err := WalkPlan(parsePlan(t, "{attempts: 2}"), func(_ StepPath, step atc.PlanConfig) (atc.PlanConfig, error) {
+					return step, nil
+				})
+				return err
+			},
+			message: "unknown step type:\n\nattempts: 2\n",
+		},
+		{
+			title: "error from the walk func",
+			run: func(t *testing.T) error {
+				var walked []string
+				_, err := WalkJob(parseJob(t, walkedJob), func(path StepPath, step atc.PlanConfig) (atc.PlanConfig, error) {
+					walked = append(walked, string(path))
+					if path == "plan[1].in_parallel[0]" {
+						return step, errStop
+					}
+
+					return step, nil
+				})
+
+				expected := []string{"plan[0].on_failure", "plan[0]", "plan[1].in_parallel[0]"}
+				if !reflect.DeepEqual(walked, expected) {
+					t.Errorf("expected the walk to stop after %v, got %v", expected, walked)
+				}
+
+				return err
+			},
+			is: errStop,
+		},
+		{
+			title: "error from the visit func",
+			run: func(t *testing.T) error {
+				return VisitPlan(parsePlan(t, walkedPlan), func(path StepPath, _ atc.PlanConfig) error {
+					if path == "try.do[0]" {
+						return errStop
+					}
+
+					return nil
+				})
+			},
+			is: errStop,
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			err := test.run(t)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if test.is != nil && !errors.Is(err, test.is) {
+				t.Errorf("expected %v, got %v", test.is, err)
+			}
+
+			if test.message != "" && err.Error() != test.message {
+				t.Errorf("expected %q, got %q", test.message, err.Error())
+			}
+		})
+	}
+}
+
+// deepPlan generates a plan nesting each kind of step to the given depth,
+// with width steps at each level.
+func deepPlan(depth int, width int) atc.PlanConfig {
+	if depth == 0 {
+		return atc.PlanConfig{
+			Task:   "leaf",
+			Ensure: &atc.PlanConfig{Put: "out"},
+		}
+	}
+
+	steps := make(atc.PlanSequence, width)
+	for i := range steps {
+		nested := deepPlan(depth-1, width)
+
+		switch i % 3 {
+		case 0:
+			steps[i] = atc.PlanConfig{Try: &nested}
+		case 1:
+			steps[i] = atc.PlanConfig{InParallel: &atc.InParallelConfig{Steps: atc.PlanSequence{nested}}}
+		default:
+			steps[i] = nested
+		}
+	}
+
+	return atc.PlanConfig{
+		Do:      &steps,
+		Failure: &atc.PlanConfig{Get: "repo"},
+	}
+}
+
+func BenchmarkWalkPlan(b *testing.B) {
+	plan := deepPlan(4, 10)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := WalkPlan(plan, func(_ StepPath, step atc.PlanConfig) (atc.PlanConfig, error) {
+			return step, nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVisitPlan(b *testing.B) {
+	plan := deepPlan(4, 10)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err := VisitPlan(plan, func(StepPath, atc.PlanConfig) error {
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package pipe2proj
+
+import (
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// externalizeWebhookTokens replaces each resource's literal webhook token with
+// a ((<resource>-webhook-token)) var, returning the vars with their original
+// values in the order of the resources. Tokens which already use a var are
+// left alone.
+func externalizeWebhookTokens(config *PipelineConfig) yaml.MapSlice {
+	var vars yaml.MapSlice
+	for i, res := range config.Resources {
+		if res.WebhookToken == "" || varRegexp.MatchString(res.WebhookToken) {
+			continue
+		}
+
+		name := res.Name + "-webhook-token"
+
+		logrus.WithFields(logrus.Fields{
+			"resource": res.Name,
+			"var":      name,
+		}).Info("externalizing webhook token")
+
+		vars = append(vars, yaml.MapItem{
+			Key:   name,
+			Value: res.WebhookToken,
+		})
+
+		config.Resources[i].WebhookToken = "((" + name + "))"
+	}
+
+	return vars
+}
+
+// mergeVars adds the vars externalized from another pipeline, e.g. webhook
+// tokens, to those so far. Pipelines sharing a resource share its var, so
+// they must agree on its value.
+func mergeVars(vars yaml.MapSlice, more yaml.MapSlice, kind string) (yaml.MapSlice, error) {
+	for _, item := range more {
+		found := false
+		for _, existing := range vars {
+			if existing.Key != item.Key {
+				continue
+			}
+
+			if existing.Value != item.Value {
+				return vars, invalidf("pipelines have different values for %s var '%s'", kind, item.Key)
+			}
+
+			found = true
+		}
+
+		if !found {
+			vars = append(vars, item)
+		}
+	}
+
+	return vars, nil
+}
+
+// externalizedVars are the vars which literal secrets in a pipeline were
+// replaced with, and their values.
+type externalizedVars struct {
+	WebhookTokens       yaml.MapSlice
+	RegistryCredentials yaml.MapSlice
+}
+
+// merge adds the vars externalized from another pipeline.
+func (vars externalizedVars) merge(more externalizedVars) (externalizedVars, error) {
+	var err error
+	vars.WebhookTokens, err = mergeVars(vars.WebhookTokens, more.WebhookTokens, "webhook token")
+	if err != nil {
+		return vars, err
+	}
+
+	vars.RegistryCredentials, err = mergeVars(vars.RegistryCredentials, more.RegistryCredentials, "registry credential")
+	if err != nil {
+		return vars, err
+	}
+
+	return vars, nil
+}